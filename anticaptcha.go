@@ -2,16 +2,23 @@ package anticaptcha
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
+// Client is safe for concurrent use by multiple goroutines once configured.
+// TaskID, Cookies, HcaptchaUserAgent and HcaptchaRespKey only hold the data of
+// the most recent solve and are kept for backward compatibility; concurrent
+// callers should use Solve and the *ByID report methods instead.
 type Client struct {
 	ClientKey                   string
 	ConnectionTimeout           int
@@ -24,6 +31,30 @@ type Client struct {
 	HcaptchaUserAgent string
 	HcaptchaRespKey   string
 	Cookies           []string
+
+	BaseURL      string
+	FallbackURLs []string
+	HTTPClient   *http.Client
+	RetryPolicy  *RetryPolicy
+	Logger       Logger
+	Providers    []Provider
+	Failover     FailoverPolicy
+	KeyPool      *KeyPool
+	Budget       *Budget
+	ProxyPool    *ProxyPool
+
+	CallbackURL     string
+	Callbacks       *CallbackHandler
+	CallbackTimeout time.Duration
+
+	mu       sync.Mutex
+	last     TaskMeta
+	limiters map[string]*rateLimiter
+}
+
+type TaskResult struct {
+	TaskMeta
+	Solution map[string]interface{}
 }
 
 type ImageSettings struct {
@@ -39,7 +70,7 @@ type ImageSettings struct {
 }
 
 type Proxy struct {
-	Type      string
+	Type      ProxyType
 	IPAddress string
 	Port      int
 	Login     string
@@ -140,7 +171,17 @@ type ImageToCoordinates struct {
 	WebsiteURL string
 }
 
-func NewClient(apiKey string) *Client {
+const DefaultBaseURL = "https://api.anti-captcha.com/"
+
+var defaultHTTPClient = &http.Client{Transport: newDefaultTransport()}
+
+func newDefaultTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = 32
+	return transport
+}
+
+func NewClient(apiKey string, options ...Option) *Client {
 	ac := Client{
 		ClientKey:                   apiKey,
 		ConnectionTimeout:           120,
@@ -148,6 +189,10 @@ func NewClient(apiKey string) *Client {
 		NormalWaitingInterval:       5,
 		IsVerbose:                   true,
 		SoftId:                      1187,
+		BaseURL:                     DefaultBaseURL,
+	}
+	for _, option := range options {
+		option(&ac)
 	}
 	return &ac
 }
@@ -165,7 +210,11 @@ func (ac *Client) SetSoftId(softId int) {
 }
 
 func (ac *Client) GetBalance() (float64, error) {
-	response, err := ac.JSONRequest("getBalance", map[string]interface{}{"clientKey": ac.ClientKey})
+	return ac.GetBalanceContext(context.Background())
+}
+
+func (ac *Client) GetBalanceContext(ctx context.Context) (float64, error) {
+	response, err := ac.JSONRequestContext(ctx, "getBalance", map[string]interface{}{"clientKey": ac.ClientKey})
 	if err != nil {
 		return 0, err
 	}
@@ -173,7 +222,11 @@ func (ac *Client) GetBalance() (float64, error) {
 }
 
 func (ac *Client) GetCreditsBalance() (float64, error) {
-	response, err := ac.JSONRequest("getBalance", map[string]interface{}{"clientKey": ac.ClientKey})
+	return ac.GetCreditsBalanceContext(context.Background())
+}
+
+func (ac *Client) GetCreditsBalanceContext(ctx context.Context) (float64, error) {
+	response, err := ac.JSONRequestContext(ctx, "getBalance", map[string]interface{}{"clientKey": ac.ClientKey})
 	if err != nil {
 		return 0, err
 	}
@@ -184,347 +237,255 @@ func (ac *Client) GetCreditsBalance() (float64, error) {
 }
 
 func (ac *Client) SolveImageFile(path string, settings ImageSettings) (string, error) {
+	return ac.SolveImageFileContext(context.Background(), path, settings)
+}
+
+func (ac *Client) SolveImageFileContext(ctx context.Context, path string, settings ImageSettings) (string, error) {
 	imageData, err := ac.ReadImageFile(path)
 	if err != nil {
 		return "", err
 	}
-	return ac.SolveImage(base64.StdEncoding.EncodeToString(imageData), settings)
+	return ac.SolveImageContext(ctx, base64.StdEncoding.EncodeToString(imageData), settings)
 }
 
 func (ac *Client) SolveImage(body string, settings ImageSettings) (string, error) {
-	task := map[string]interface{}{
-		"type":         "ImageToTextTask",
-		"body":         body,
-		"phrase":       settings.Phrase,
-		"case":         settings.CaseSensitive,
-		"numeric":      settings.Numeric,
-		"comment":      settings.Comment,
-		"math":         settings.MathOperation,
-		"minLength":    settings.MinLength,
-		"maxLength":    settings.MaxLength,
-		"languagePool": settings.LanguagePool,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveImageContext(context.Background(), body, settings)
+}
+
+func (ac *Client) SolveImageContext(ctx context.Context, body string, settings ImageSettings) (string, error) {
+	solution, err := ac.SolveImageResult(ctx, body, settings)
 	if err != nil {
 		return "", err
 	}
-	return solution["text"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Text, nil
 }
 
 func (ac *Client) ReportIncorrectImageCaptcha() error {
-	_, err := ac.JSONRequest("reportIncorrectImageCaptcha", map[string]interface{}{
+	return ac.reportLast(context.Background(), "reportIncorrectImageCaptcha")
+}
+
+func (ac *Client) ReportIncorrectImageCaptchaByID(ctx context.Context, taskID int) error {
+	_, err := ac.JSONRequestContext(ctx, "reportIncorrectImageCaptcha", map[string]interface{}{
 		"clientKey": ac.ClientKey,
-		"taskId":    ac.TaskID,
+		"taskId":    taskID,
 	})
 	return err
 }
 
 func (ac *Client) SolveRecaptchaV2(recaptcha RecaptchaV2) (string, error) {
-	task := map[string]interface{}{
-		"type":                "RecaptchaV2TaskProxyless",
-		"websiteURL":          recaptcha.WebsiteURL,
-		"websiteKey":          recaptcha.WebsiteKey,
-		"websiteSToken":       recaptcha.WebsiteSToken,
-		"recaptchaDataSValue": recaptcha.DataSValue,
-	}
-	if recaptcha.IsInvisible {
-		task["isInvisible"] = true
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveRecaptchaV2Context(context.Background(), recaptcha)
+}
+
+func (ac *Client) SolveRecaptchaV2Context(ctx context.Context, recaptcha RecaptchaV2) (string, error) {
+	solution, err := ac.SolveRecaptchaV2Result(ctx, recaptcha)
 	if err != nil {
 		return "", err
 	}
-	if cookies, ok := solution["cookies"].([]string); ok {
-		ac.Cookies = cookies
-	}
-	return solution["gRecaptchaResponse"].(string), nil
+	ac.rememberRecaptcha(solution)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveRecaptchaV2ProxyOn(recaptcha RecaptchaV2) (string, error) {
-	task := map[string]interface{}{
-		"type":                "RecaptchaV2Task",
-		"websiteURL":          recaptcha.WebsiteURL,
-		"websiteKey":          recaptcha.WebsiteKey,
-		"websiteSToken":       recaptcha.WebsiteSToken,
-		"recaptchaDataSValue": recaptcha.DataSValue,
-		"userAgent":           recaptcha.UserAgent,
-		"proxyType":           recaptcha.Proxy.Type,
-		"proxyAddress":        recaptcha.Proxy.IPAddress,
-		"proxyPort":           recaptcha.Proxy.Port,
-		"proxyLogin":          recaptcha.Proxy.Login,
-		"proxyPassword":       recaptcha.Proxy.Password,
-	}
-	if recaptcha.IsInvisible {
-		task["isInvisible"] = true
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveRecaptchaV2ProxyOnContext(context.Background(), recaptcha)
+}
+
+func (ac *Client) SolveRecaptchaV2ProxyOnContext(ctx context.Context, recaptcha RecaptchaV2) (string, error) {
+	solution, err := ac.SolveRecaptchaV2ProxyOnResult(ctx, recaptcha)
 	if err != nil {
 		return "", err
 	}
-	if cookies, ok := solution["cookies"].([]string); ok {
-		ac.Cookies = cookies
-	}
-	return solution["gRecaptchaResponse"].(string), nil
+	ac.rememberRecaptcha(solution)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveRecaptchaV3(recaptcha RecaptchaV3) (string, error) {
-	task := map[string]interface{}{
-		"type":         "RecaptchaV3TaskProxyless",
-		"websiteURL":   recaptcha.WebsiteURL,
-		"websiteKey":   recaptcha.WebsiteKey,
-		"minScore":     recaptcha.MinScore,
-		"pageAction":   recaptcha.PageAction,
-		"isEnterprise": recaptcha.IsEnterprise,
-		"apiDomain":    recaptcha.APIDomain,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveRecaptchaV3Context(context.Background(), recaptcha)
+}
+
+func (ac *Client) SolveRecaptchaV3Context(ctx context.Context, recaptcha RecaptchaV3) (string, error) {
+	solution, err := ac.SolveRecaptchaV3Result(ctx, recaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["gRecaptchaResponse"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveHcaptcha(hcaptcha Hcaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":              "HCaptchaTaskProxyless",
-		"websiteURL":        hcaptcha.WebsiteURL,
-		"websiteKey":        hcaptcha.WebsiteKey,
-		"isEnterprise":      hcaptcha.IsEnterprise,
-		"enterprisePayload": hcaptcha.EnterprisePayload,
-	}
-	if hcaptcha.IsInvisible {
-		task["isInvisible"] = true
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveHcaptchaContext(context.Background(), hcaptcha)
+}
+
+func (ac *Client) SolveHcaptchaContext(ctx context.Context, hcaptcha Hcaptcha) (string, error) {
+	solution, err := ac.SolveHcaptchaResult(ctx, hcaptcha)
 	if err != nil {
 		return "", err
 	}
-	if userAgent, ok := solution["userAgent"].(string); ok {
-		ac.HcaptchaUserAgent = userAgent
-	}
-	if respKey, ok := solution["respKey"].(string); ok {
-		ac.HcaptchaRespKey = respKey
-	}
-	return solution["gRecaptchaResponse"].(string), nil
+	ac.rememberHcaptcha(solution)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveHcaptchaProxyOn(hcaptcha Hcaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":              "HCaptchaTask",
-		"websiteURL":        hcaptcha.WebsiteURL,
-		"websiteKey":        hcaptcha.WebsiteKey,
-		"isEnterprise":      hcaptcha.IsEnterprise,
-		"enterprisePayload": hcaptcha.EnterprisePayload,
-		"proxyType":         hcaptcha.Proxy.Type,
-		"proxyAddress":      hcaptcha.Proxy.IPAddress,
-		"proxyPort":         hcaptcha.Proxy.Port,
-		"proxyLogin":        hcaptcha.Proxy.Login,
-		"proxyPassword":     hcaptcha.Proxy.Password,
-	}
-	if hcaptcha.IsInvisible {
-		task["isInvisible"] = true
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveHcaptchaProxyOnContext(context.Background(), hcaptcha)
+}
+
+func (ac *Client) SolveHcaptchaProxyOnContext(ctx context.Context, hcaptcha Hcaptcha) (string, error) {
+	solution, err := ac.SolveHcaptchaProxyOnResult(ctx, hcaptcha)
 	if err != nil {
 		return "", err
 	}
-	if userAgent, ok := solution["userAgent"].(string); ok {
-		ac.HcaptchaUserAgent = userAgent
-	}
-	if respKey, ok := solution["respKey"].(string); ok {
-		ac.HcaptchaRespKey = respKey
-	}
-	return solution["gRecaptchaResponse"].(string), nil
+	ac.rememberHcaptcha(solution)
+	return solution.Token, nil
+}
+
+func (ac *Client) ReportIncorrectHcaptcha() error {
+	return ac.reportLast(context.Background(), "reportIncorrectHcaptcha")
+}
+
+func (ac *Client) ReportIncorrectHcaptchaByID(ctx context.Context, taskID int) error {
+	_, err := ac.JSONRequestContext(ctx, "reportIncorrectHcaptcha", map[string]interface{}{
+		"clientKey": ac.ClientKey,
+		"taskId":    taskID,
+	})
+	return err
 }
 
 func (ac *Client) SolveFunCaptcha(funcaptcha FunCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":                     "FunCaptchaTaskProxyless",
-		"websiteURL":               funcaptcha.WebsiteURL,
-		"websitePublicKey":         funcaptcha.WebsitePublicKey,
-		"funcaptchaApiJSSubdomain": funcaptcha.ApiSubdomain,
-		"data":                     funcaptcha.DataBlob,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveFunCaptchaContext(context.Background(), funcaptcha)
+}
+
+func (ac *Client) SolveFunCaptchaContext(ctx context.Context, funcaptcha FunCaptcha) (string, error) {
+	solution, err := ac.SolveFunCaptchaResult(ctx, funcaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveFunCaptchaProxyOn(funcaptcha FunCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":                     "FunCaptchaTask",
-		"websiteURL":               funcaptcha.WebsiteURL,
-		"websitePublicKey":         funcaptcha.WebsitePublicKey,
-		"funcaptchaApiJSSubdomain": funcaptcha.ApiSubdomain,
-		"data":                     funcaptcha.DataBlob,
-		"proxyType":                funcaptcha.Proxy.Type,
-		"proxyAddress":             funcaptcha.Proxy.IPAddress,
-		"proxyPort":                funcaptcha.Proxy.Port,
-		"proxyLogin":               funcaptcha.Proxy.Login,
-		"proxyPassword":            funcaptcha.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveFunCaptchaProxyOnContext(context.Background(), funcaptcha)
+}
+
+func (ac *Client) SolveFunCaptchaProxyOnContext(ctx context.Context, funcaptcha FunCaptcha) (string, error) {
+	solution, err := ac.SolveFunCaptchaProxyOnResult(ctx, funcaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveTurnstile(turnstile Turnstile) (string, error) {
-	task := map[string]interface{}{
-		"type":        "TurnstileTaskProxyless",
-		"websiteURL":  turnstile.WebsiteURL,
-		"websiteKey":  turnstile.WebsiteKey,
-		"action":      turnstile.Action,
-		"cData":       turnstile.CData,
-		"chlPageData": turnstile.ChlPageData,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveTurnstileContext(context.Background(), turnstile)
+}
+
+func (ac *Client) SolveTurnstileContext(ctx context.Context, turnstile Turnstile) (string, error) {
+	solution, err := ac.SolveTurnstileResult(ctx, turnstile)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveTurnstileProxyOn(turnstile Turnstile) (string, error) {
-	task := map[string]interface{}{
-		"type":          "TurnstileTask",
-		"websiteURL":    turnstile.WebsiteURL,
-		"websiteKey":    turnstile.WebsiteKey,
-		"action":        turnstile.Action,
-		"cData":         turnstile.CData,
-		"chlPageData":   turnstile.ChlPageData,
-		"proxyType":     turnstile.Proxy.Type,
-		"proxyAddress":  turnstile.Proxy.IPAddress,
-		"proxyPort":     turnstile.Proxy.Port,
-		"proxyLogin":    turnstile.Proxy.Login,
-		"proxyPassword": turnstile.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveTurnstileProxyOnContext(context.Background(), turnstile)
+}
+
+func (ac *Client) SolveTurnstileProxyOnContext(ctx context.Context, turnstile Turnstile) (string, error) {
+	solution, err := ac.SolveTurnstileProxyOnResult(ctx, turnstile)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveProsopo(prosopo Prosopo) (string, error) {
-	task := map[string]interface{}{
-		"type":       "ProsopoTaskProxyless",
-		"websiteURL": prosopo.WebsiteURL,
-		"websiteKey": prosopo.WebsiteKey,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveProsopoContext(context.Background(), prosopo)
+}
+
+func (ac *Client) SolveProsopoContext(ctx context.Context, prosopo Prosopo) (string, error) {
+	solution, err := ac.SolveProsopoResult(ctx, prosopo)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveProsopoProxyOn(prosopo Prosopo) (string, error) {
-	task := map[string]interface{}{
-		"type":          "ProsopoTask",
-		"websiteURL":    prosopo.WebsiteURL,
-		"websiteKey":    prosopo.WebsiteKey,
-		"proxyType":     prosopo.Proxy.Type,
-		"proxyAddress":  prosopo.Proxy.IPAddress,
-		"proxyPort":     prosopo.Proxy.Port,
-		"proxyLogin":    prosopo.Proxy.Login,
-		"proxyPassword": prosopo.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveProsopoProxyOnContext(context.Background(), prosopo)
+}
+
+func (ac *Client) SolveProsopoProxyOnContext(ctx context.Context, prosopo Prosopo) (string, error) {
+	solution, err := ac.SolveProsopoProxyOnResult(ctx, prosopo)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveFriendlyCaptcha(friendlyCaptcha FriendlyCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":       "FriendlyCaptchaTaskProxyless",
-		"websiteURL": friendlyCaptcha.WebsiteURL,
-		"websiteKey": friendlyCaptcha.WebsiteKey,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveFriendlyCaptchaContext(context.Background(), friendlyCaptcha)
+}
+
+func (ac *Client) SolveFriendlyCaptchaContext(ctx context.Context, friendlyCaptcha FriendlyCaptcha) (string, error) {
+	solution, err := ac.SolveFriendlyCaptchaResult(ctx, friendlyCaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveFriendlyCaptchaProxyOn(friendlyCaptcha FriendlyCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":          "FriendlyCaptchaTask",
-		"websiteURL":    friendlyCaptcha.WebsiteURL,
-		"websiteKey":    friendlyCaptcha.WebsiteKey,
-		"proxyType":     friendlyCaptcha.Proxy.Type,
-		"proxyAddress":  friendlyCaptcha.Proxy.IPAddress,
-		"proxyPort":     friendlyCaptcha.Proxy.Port,
-		"proxyLogin":    friendlyCaptcha.Proxy.Login,
-		"proxyPassword": friendlyCaptcha.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveFriendlyCaptchaProxyOnContext(context.Background(), friendlyCaptcha)
+}
+
+func (ac *Client) SolveFriendlyCaptchaProxyOnContext(ctx context.Context, friendlyCaptcha FriendlyCaptcha) (string, error) {
+	solution, err := ac.SolveFriendlyCaptchaProxyOnResult(ctx, friendlyCaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveAmazon(amazonCaptcha AmazonCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":            "AmazonTaskProxyless",
-		"websiteURL":      amazonCaptcha.WebsiteURL,
-		"websiteKey":      amazonCaptcha.WebsiteKey,
-		"wafType":         amazonCaptcha.WafType,
-		"iv":              amazonCaptcha.Iv,
-		"context":         amazonCaptcha.Context,
-		"captchaScript":   amazonCaptcha.CaptchaScript,
-		"challengeScript": amazonCaptcha.ChallengeScript,
-		"jsapiScript":     amazonCaptcha.JsapiScript,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveAmazonContext(context.Background(), amazonCaptcha)
+}
+
+func (ac *Client) SolveAmazonContext(ctx context.Context, amazonCaptcha AmazonCaptcha) (string, error) {
+	solution, err := ac.SolveAmazonResult(ctx, amazonCaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveAmazonProxyOn(amazonCaptcha AmazonCaptcha) (string, error) {
-	task := map[string]interface{}{
-		"type":            "AmazonTask",
-		"websiteURL":      amazonCaptcha.WebsiteURL,
-		"websiteKey":      amazonCaptcha.WebsiteKey,
-		"wafType":         amazonCaptcha.WafType,
-		"iv":              amazonCaptcha.Iv,
-		"context":         amazonCaptcha.Context,
-		"captchaScript":   amazonCaptcha.CaptchaScript,
-		"challengeScript": amazonCaptcha.ChallengeScript,
-		"jsapiScript":     amazonCaptcha.JsapiScript,
-		"proxyType":       amazonCaptcha.Proxy.Type,
-		"proxyAddress":    amazonCaptcha.Proxy.IPAddress,
-		"proxyPort":       amazonCaptcha.Proxy.Port,
-		"proxyLogin":      amazonCaptcha.Proxy.Login,
-		"proxyPassword":   amazonCaptcha.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveAmazonProxyOnContext(context.Background(), amazonCaptcha)
+}
+
+func (ac *Client) SolveAmazonProxyOnContext(ctx context.Context, amazonCaptcha AmazonCaptcha) (string, error) {
+	solution, err := ac.SolveAmazonProxyOnResult(ctx, amazonCaptcha)
 	if err != nil {
 		return "", err
 	}
-	return solution["token"].(string), nil
+	ac.rememberTask(solution.TaskMeta)
+	return solution.Token, nil
 }
 
 func (ac *Client) SolveGeeTest(geetest GeeTest) (map[string]interface{}, error) {
-	task := map[string]interface{}{
-		"type":                      "GeeTestTaskProxyless",
-		"websiteURL":                geetest.WebsiteURL,
-		"gt":                        geetest.Gt,
-		"challenge":                 geetest.Challenge,
-		"geetestApiServerSubdomain": geetest.ApiSubdomain,
-		"version":                   geetest.Version,
-		"initParameters":            geetest.InitParameters,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveGeeTestContext(context.Background(), geetest)
+}
+
+func (ac *Client) SolveGeeTestContext(ctx context.Context, geetest GeeTest) (map[string]interface{}, error) {
+	solution, err := CreateTaskAndWaitForResultContext(ctx, ac, geeTestTask(geetest, false))
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
@@ -532,21 +493,17 @@ func (ac *Client) SolveGeeTest(geetest GeeTest) (map[string]interface{}, error)
 }
 
 func (ac *Client) SolveGeeTestProxyOn(geetest GeeTest) (map[string]interface{}, error) {
-	task := map[string]interface{}{
-		"type":                      "GeeTestTask",
-		"websiteURL":                geetest.WebsiteURL,
-		"gt":                        geetest.Gt,
-		"challenge":                 geetest.Challenge,
-		"geetestApiServerSubdomain": geetest.ApiSubdomain,
-		"version":                   geetest.Version,
-		"initParameters":            geetest.InitParameters,
-		"proxyType":                 geetest.Proxy.Type,
-		"proxyAddress":              geetest.Proxy.IPAddress,
-		"proxyPort":                 geetest.Proxy.Port,
-		"proxyLogin":                geetest.Proxy.Login,
-		"proxyPassword":             geetest.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveGeeTestProxyOnContext(context.Background(), geetest)
+}
+
+func (ac *Client) SolveGeeTestProxyOnContext(ctx context.Context, geetest GeeTest) (map[string]interface{}, error) {
+	proxy, err := ac.proxyFor(geetest.Proxy, geetest.WebsiteURL)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+	geetest.Proxy = proxy
+	solution, err := CreateTaskAndWaitForResultContext(ctx, ac, geeTestTask(geetest, true))
+	ac.reportProxy(proxy, err)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
@@ -554,128 +511,261 @@ func (ac *Client) SolveGeeTestProxyOn(geetest GeeTest) (map[string]interface{},
 }
 
 func (ac *Client) SolveAntiGate(antigate AntiGate) (map[string]interface{}, error) {
-	task := map[string]interface{}{
-		"type":              "AntiGateTask",
-		"websiteURL":        antigate.WebsiteURL,
-		"templateName":      antigate.TemplateName,
-		"variables":         antigate.Variables,
-		"domainsOfInterest": antigate.DomainsOfInterest,
-		"proxyType":         antigate.Proxy.Type,
-		"proxyAddress":      antigate.Proxy.IPAddress,
-		"proxyPort":         antigate.Proxy.Port,
-		"proxyLogin":        antigate.Proxy.Login,
-		"proxyPassword":     antigate.Proxy.Password,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+	return ac.SolveAntiGateContext(context.Background(), antigate)
+}
+
+func (ac *Client) SolveAntiGateContext(ctx context.Context, antigate AntiGate) (map[string]interface{}, error) {
+	proxy, err := ac.optionalProxy(antigate.Proxy, antigate.WebsiteURL)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+	antigate.Proxy = proxy
+	solution, err := CreateTaskAndWaitForResultContext(ctx, ac, antiGateTask(antigate))
+	ac.reportProxy(proxy, err)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
 	return solution, nil
 }
 
-func (ac *Client) SolveImageToCoordinates(body string, settings ImageToCoordinates) ([]interface{}, error) { //, phrase bool, caseSensitive bool, isNumeric bool
-	task := map[string]interface{}{
-		"type":       "ImageToCoordinatesTask",
-		"body":       body,
-		"comment":    settings.Comment,
-		"mode":       settings.Mode,
-		"websiteURL": settings.WebsiteURL,
-	}
-	solution, err := CreateTaskAndWaitForResult(ac, task)
+func (ac *Client) SolveImageToCoordinates(body string, settings ImageToCoordinates) ([]interface{}, error) {
+	return ac.SolveImageToCoordinatesContext(context.Background(), body, settings)
+}
+
+func (ac *Client) SolveImageToCoordinatesContext(ctx context.Context, body string, settings ImageToCoordinates) ([]interface{}, error) {
+	solution, err := CreateTaskAndWaitForResultContext(ctx, ac, imageToCoordinatesTask(body, settings))
 	if err != nil {
 		return []interface{}{}, err
 	}
-	return solution["coordinates"].([]interface{}), nil
+	coordinates, _ := solution["coordinates"].([]interface{})
+	return coordinates, nil
 }
 
-func CreateTaskAndWaitForResult(ac *Client, task map[string]interface{}) (map[string]interface{}, error) {
-	payload := map[string]interface{}{
-		"clientKey": ac.ClientKey,
-		"task":      task,
-		"softId":    ac.SoftId,
+func (ac *Client) rememberTask(meta TaskMeta) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.TaskID = meta.TaskID
+	ac.last = meta
+}
+
+func (ac *Client) rememberRecaptcha(solution *RecaptchaSolution) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.TaskID = solution.TaskID
+	ac.last = solution.TaskMeta
+	if solution.Cookies != nil {
+		ac.Cookies = make([]string, 0, len(solution.Cookies))
+		for name, value := range solution.Cookies {
+			ac.Cookies = append(ac.Cookies, name+"="+value)
+		}
 	}
-	taskCreateResult, err := ac.JSONRequest("createTask", payload)
+}
+
+func (ac *Client) rememberHcaptcha(solution *HcaptchaSolution) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.TaskID = solution.TaskID
+	ac.last = solution.TaskMeta
+	ac.HcaptchaUserAgent = solution.UserAgent
+	ac.HcaptchaRespKey = solution.RespKey
+}
+
+func CreateTaskAndWaitForResult(ac *Client, task map[string]interface{}) (map[string]interface{}, error) {
+	return CreateTaskAndWaitForResultContext(context.Background(), ac, task)
+}
+
+func CreateTaskAndWaitForResultContext(ctx context.Context, ac *Client, task map[string]interface{}) (map[string]interface{}, error) {
+	result, err := ac.Solve(ctx, task)
 	if err != nil {
 		return nil, err
 	}
-	if taskID, ok := taskCreateResult["taskId"].(float64); ok {
-		ac.TaskID = int(taskID)
-		solution, err := ac.WaitForResult(ac.TaskID)
-		if err != nil {
+	ac.rememberTask(result.TaskMeta)
+	return result.Solution, nil
+}
+
+// Solve creates the task, waits for its solution and returns it together with
+// the task ID. It does not touch the Client's legacy result fields.
+func (ac *Client) Solve(ctx context.Context, task map[string]interface{}) (*TaskResult, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := ac.createTaskAndWaitForResult(ctx, task)
+		if err == nil || !ac.RetryPolicy.allows(attempt) || !ac.RetryPolicy.recreatesTask(err) {
+			return result, err
+		}
+		backoff := ac.RetryPolicy.Backoff(attempt)
+		ac.logger().Log(ctx, LogLevelWarn, "recreating task",
+			"task_type", task["type"],
+			"attempt", attempt,
+			"error", err,
+			"backoff", backoff,
+		)
+		if err := sleepContext(ctx, backoff); err != nil {
 			return nil, err
 		}
-		return solution, nil
 	}
-	return nil, errors.New(taskCreateResult["errorCode"].(string))
+}
+
+func (ac *Client) createTaskAndWaitForResult(ctx context.Context, task map[string]interface{}) (*TaskResult, error) {
+	t, err := ac.CreateTask(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return t.Wait(ctx)
 }
 
 func (ac *Client) GetCookies() []string {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
 	return ac.Cookies
 }
 
+// lastTask returns the task of the most recent solve. TaskID may have been
+// set by the caller, in which case only the ID is known.
+func (ac *Client) lastTask() TaskMeta {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ac.TaskID != ac.last.TaskID {
+		return TaskMeta{TaskID: ac.TaskID}
+	}
+	return ac.last
+}
+
 func (ac *Client) ReportIncorrectRecaptcha() error {
-	_, err := ac.JSONRequest("reportIncorrectRecaptcha", map[string]interface{}{
+	return ac.reportLast(context.Background(), "reportIncorrectRecaptcha")
+}
+
+func (ac *Client) ReportIncorrectRecaptchaByID(ctx context.Context, taskID int) error {
+	_, err := ac.JSONRequestContext(ctx, "reportIncorrectRecaptcha", map[string]interface{}{
 		"clientKey": ac.ClientKey,
-		"taskId":    ac.TaskID,
+		"taskId":    taskID,
 	})
 	return err
 }
 
 func (ac *Client) ReportCorrectRecaptcha() error {
-	_, err := ac.JSONRequest("reportCorrectRecaptcha", map[string]interface{}{
+	return ac.reportLast(context.Background(), "reportCorrectRecaptcha")
+}
+
+func (ac *Client) ReportCorrectRecaptchaByID(ctx context.Context, taskID int) error {
+	_, err := ac.JSONRequestContext(ctx, "reportCorrectRecaptcha", map[string]interface{}{
 		"clientKey": ac.ClientKey,
-		"taskId":    ac.TaskID,
+		"taskId":    taskID,
 	})
 	return err
 }
 
 func (ac *Client) WaitForResult(taskId int) (map[string]interface{}, error) {
-	if ac.IsVerbose {
-		fmt.Println("created task with ID", taskId)
-		fmt.Println("waiting", ac.FirstAttemptWaitingInterval, "seconds")
-	}
-	time.Sleep(time.Duration(ac.FirstAttemptWaitingInterval) * time.Second)
-
-	for taskId > 0 {
-		checkResult, err := ac.JSONRequest("getTaskResult", map[string]interface{}{
-			"clientKey": ac.ClientKey,
-			"taskId":    taskId,
-		})
-		if err != nil {
+	return ac.WaitForResultContext(context.Background(), taskId)
+}
+
+func (ac *Client) WaitForResultContext(ctx context.Context, taskId int) (map[string]interface{}, error) {
+	result, err := ac.TaskByID(taskId).wait(ctx, time.Duration(ac.FirstAttemptWaitingInterval)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return result.Solution, nil
+}
+
+func (ac *Client) JSONRequest(methodName string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return ac.JSONRequestContext(context.Background(), methodName, payload)
+}
+
+func (ac *Client) JSONRequestContext(ctx context.Context, methodName string, payload map[string]interface{}) (map[string]interface{}, error) {
+	return ac.request(ctx, ac.defaultProvider(), methodName, payload)
+}
+
+func (ac *Client) request(ctx context.Context, provider Provider, methodName string, payload map[string]interface{}) (map[string]interface{}, error) {
+	for attempt := 1; ; attempt++ {
+		if err := ac.waitRateLimit(ctx, methodName); err != nil {
 			return nil, err
 		}
-		if status, ok := checkResult["status"].(string); ok && status == "ready" {
-			return checkResult["solution"].(map[string]interface{}), nil
-		}
-		if status, ok := checkResult["status"].(string); ok && status == "processing" && ac.IsVerbose {
-			fmt.Println("captcha result is not yet ready")
+		response, err := ac.jsonRequest(ctx, provider, methodName, payload)
+		if err == nil || !ac.RetryPolicy.allows(attempt) || !ac.RetryPolicy.retriesRequest(methodName, err) {
+			return response, err
 		}
-		if ac.IsVerbose {
-			fmt.Println("waiting", ac.NormalWaitingInterval, "seconds")
+		backoff := ac.RetryPolicy.Backoff(attempt)
+		ac.logger().Log(ctx, LogLevelWarn, "retrying request",
+			"method", methodName,
+			"attempt", attempt,
+			"error", err,
+			"backoff", backoff,
+		)
+		if err := sleepContext(ctx, backoff); err != nil {
+			return nil, err
 		}
-		time.Sleep(time.Duration(ac.NormalWaitingInterval) * time.Second)
 	}
-	return nil, errors.New("ERROR_NO_SLOT_AVAILABLE")
 }
 
-func (ac *Client) JSONRequest(methodName string, payload map[string]interface{}) (map[string]interface{}, error) {
-	url := "https://api.anti-captcha.com/" + methodName
-
+func (ac *Client) jsonRequest(ctx context.Context, provider Provider, methodName string, payload map[string]interface{}) (map[string]interface{}, error) {
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
-	client := &http.Client{
-		Timeout: time.Duration(ac.ConnectionTimeout) * time.Second,
+
+	var body []byte
+	for _, baseURL := range provider.endpoints() {
+		body, err = ac.post(ctx, endpointURL(baseURL, methodName), jsonPayload)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !repeatable(methodName, err) {
+			return nil, err
+		}
+		ac.logger().Log(ctx, LogLevelWarn, "endpoint unreachable",
+			"method", methodName,
+			"provider", provider.Name,
+			"endpoint", baseURL,
+			"error", err,
+		)
 	}
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]interface{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	errorID, ok := response["errorId"].(float64)
+	if !ok {
+		// getQueueStats replies with the bare statistics.
+		if _, present := response["errorId"]; present || methodName != "getQueueStats" {
+			return nil, ErrInvalidResponse
+		}
+	}
+	if errorID > 0 {
+		taskID, _ := payload["taskId"].(int)
+		apiErr := newAPIError(response, methodName, taskID)
+		ac.logger().Log(ctx, LogLevelError, "api error",
+			"method", methodName,
+			"task_id", apiErr.TaskID,
+			"error_id", apiErr.ID,
+			"error_code", apiErr.Code,
+			"description", apiErr.Description,
+		)
+		return nil, apiErr
+	}
+	return response, nil
+}
+
+func (ac *Client) post(ctx context.Context, url string, jsonPayload []byte) ([]byte, error) {
+	if ac.ConnectionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ac.ConnectionTimeout)*time.Second)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := client.Do(req)
+	resp, err := ac.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -685,23 +775,45 @@ func (ac *Client) JSONRequest(methodName string, payload map[string]interface{})
 	if err != nil {
 		return nil, err
 	}
+	// Error pages of gateways and load balancers are treated like an
+	// unreachable endpoint.
+	if resp.StatusCode >= 500 || resp.StatusCode >= 300 && !json.Valid(body) {
+		return nil, &EndpointError{URL: url, StatusCode: resp.StatusCode}
+	}
+	return body, nil
+}
 
-	var response map[string]interface{}
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+// unreachable reports whether err means the endpoint could not be used at
+// all, as opposed to an API error.
+func unreachable(err error) bool {
+	var netErr net.Error
+	var endpointErr *EndpointError
+	return errors.As(err, &netErr) || errors.As(err, &endpointErr)
+}
+
+func (ac *Client) httpClient() *http.Client {
+	if ac.HTTPClient != nil {
+		return ac.HTTPClient
 	}
+	return defaultHTTPClient
+}
+
+func endpointURL(baseURL string, methodName string) string {
+	return strings.TrimRight(baseURL, "/") + "/" + methodName
+}
 
-	if response["errorId"] == nil {
-		return nil, errors.New("Incorrect API response, something is wrong")
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
 	}
-	if response["errorId"].(float64) > 0 {
-		if ac.IsVerbose {
-			fmt.Println("Received API error", response["errorCode"], ":", response["errorDescription"])
-		}
-		return nil, errors.New(response["errorCode"].(string))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
-	return response, nil
 }
 
 func (ac *Client) ReadImageFile(filePath string) ([]byte, error) {
@@ -0,0 +1,389 @@
+// Package anticaptchatest provides an in-process fake of the anti-captcha API
+// for integration tests.
+package anticaptchatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+)
+
+// ClientKey is accepted by every Server.
+const ClientKey = "anticaptchatest-client-key"
+
+// Request is an API call received by the Server.
+type Request struct {
+	Method  string
+	Payload map[string]interface{}
+}
+
+// Server is a fake anti-captcha API built on httptest.Server. It implements
+// createTask, getTaskResult, getBalance, pushAntiGateVariable, the report
+// methods and the stats methods, and posts callbacks with PostCallback. Its
+// behaviour can be scripted while it is running.
+type Server struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	keys             map[string]bool
+	balance          float64
+	keyBalances      map[string]float64
+	cost             float64
+	latency          time.Duration
+	processingRounds int
+	solutions        map[string]map[string]interface{}
+	responses        map[string]map[string]interface{}
+	errors           map[string][]string
+	requests         []Request
+	tasks            map[int]*task
+	lastTaskID       int
+}
+
+type task struct {
+	payload     map[string]interface{}
+	callbackURL string
+	rounds      int
+	createTime  time.Time
+	endTime     time.Time
+}
+
+var defaultSolution = map[string]interface{}{
+	"text":               "anticaptchatest",
+	"gRecaptchaResponse": "anticaptchatest-token",
+	"token":              "anticaptchatest-token",
+	"userAgent":          "anticaptchatest-user-agent",
+	"respKey":            "anticaptchatest-resp-key",
+}
+
+// errorIDs holds the errorId values of common error codes. Other injected
+// codes are returned with errorId 1.
+var errorIDs = map[string]int{
+	"ERROR_KEY_DOES_NOT_EXIST": 1,
+	"ERROR_NO_SLOT_AVAILABLE":  2,
+	"ERROR_ZERO_BALANCE":       10,
+	"ERROR_CAPTCHA_UNSOLVABLE": 12,
+	"ERROR_NO_SUCH_CAPCHA_ID":  16,
+}
+
+// NewServer starts a Server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		keys:        map[string]bool{ClientKey: true},
+		balance:     10,
+		keyBalances: make(map[string]float64),
+		cost:        0.002,
+		solutions:   make(map[string]map[string]interface{}),
+		responses:   make(map[string]map[string]interface{}),
+		errors:      make(map[string][]string),
+		tasks:       make(map[int]*task),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a client which talks to the Server with ClientKey and polls
+// without waiting. It does not log unless options set a Logger.
+func (s *Server) Client(options ...anticaptcha.Option) *anticaptcha.Client {
+	options = append([]anticaptcha.Option{
+		anticaptcha.WithBaseURL(s.URL),
+		anticaptcha.WithHTTPClient(s.Server.Client()),
+	}, options...)
+	ac := anticaptcha.NewClient(ClientKey, options...)
+	ac.FirstAttemptWaitingInterval = 0
+	ac.NormalWaitingInterval = 0
+	if ac.Logger == nil {
+		ac.ShutUp()
+	}
+	return ac
+}
+
+// AddKey makes the Server accept another client key.
+func (s *Server) AddKey(clientKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[clientKey] = true
+}
+
+func (s *Server) SetBalance(balance float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balance = balance
+}
+
+// SetKeyBalance makes the Server accept clientKey and report balance for it
+// from getBalance, instead of the balance shared by all keys.
+func (s *Server) SetKeyBalance(clientKey string, balance float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[clientKey] = true
+	s.keyBalances[clientKey] = balance
+}
+
+// SetCost sets the cost reported for each solved task. It is also deducted
+// from the balance.
+func (s *Server) SetCost(cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cost = cost
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetProcessingRounds sets how many getTaskResult calls report "processing"
+// before a task created afterwards is ready.
+func (s *Server) SetProcessingRounds(rounds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.processingRounds = rounds
+}
+
+// SetSolution sets the solution returned for tasks of taskType.
+func (s *Server) SetSolution(taskType string, solution map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.solutions[taskType] = solution
+}
+
+// SetResponse sets a canned successful response for method, replacing the
+// Server's own handling of it. Like the real API, getQueueStats responses are
+// sent without an errorId.
+func (s *Server) SetResponse(method string, response map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[method] = response
+}
+
+// InjectError makes the next call of method fail with the error code.
+// Injected errors are queued and consumed one per call.
+func (s *Server) InjectError(method string, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[method] = append(s.errors[method], code)
+}
+
+// Requests returns the calls of method received so far, or all calls when
+// method is empty.
+func (s *Server) Requests(method string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, request := range s.requests {
+		if method == "" || request.Method == method {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// Tasks returns the task payloads received by createTask so far.
+func (s *Server) Tasks() []map[string]interface{} {
+	var tasks []map[string]interface{}
+	for _, request := range s.Requests("createTask") {
+		if task, ok := request.Payload["task"].(map[string]interface{}); ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// PostCallback solves the task now and posts its result to the callbackUrl
+// it was created with, like the real API does. An error injected for
+// getTaskResult is posted instead of the result. It returns the status code
+// of the callback handler.
+func (s *Server) PostCallback(taskID int) (int, error) {
+	s.mu.Lock()
+	t, ok := s.tasks[taskID]
+	if !ok || t.callbackURL == "" {
+		s.mu.Unlock()
+		return 0, errors.New("anticaptchatest: no task with a callbackUrl")
+	}
+	var response map[string]interface{}
+	if codes := s.errors["getTaskResult"]; len(codes) > 0 {
+		s.errors["getTaskResult"] = codes[1:]
+		response = errorResponse(codes[0])
+	} else {
+		t.rounds = 0
+		response = s.getTaskResult(map[string]interface{}{"taskId": float64(taskID)})
+	}
+	response["taskId"] = taskID
+	callbackURL := t.callbackURL
+	s.mu.Unlock()
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.Post(callbackURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("anticaptchatest: posting callback: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/")
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: method, Payload: payload})
+	latency := s.latency
+	response := s.respond(method, payload)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) respond(method string, payload map[string]interface{}) map[string]interface{} {
+	if codes := s.errors[method]; len(codes) > 0 {
+		s.errors[method] = codes[1:]
+		return errorResponse(codes[0])
+	}
+	if method != "getQueueStats" {
+		if key, _ := payload["clientKey"].(string); !s.keys[key] {
+			return errorResponse("ERROR_KEY_DOES_NOT_EXIST")
+		}
+	}
+	if response, ok := s.responses[method]; ok {
+		if method == "getQueueStats" {
+			return response
+		}
+		return success(response)
+	}
+
+	switch method {
+	case "createTask":
+		return s.createTask(payload)
+	case "getTaskResult":
+		return s.getTaskResult(payload)
+	case "getBalance":
+		key, _ := payload["clientKey"].(string)
+		balance, ok := s.keyBalances[key]
+		if !ok {
+			balance = s.balance
+		}
+		return success(map[string]interface{}{"balance": balance})
+	case "reportIncorrectImageCaptcha", "reportIncorrectRecaptcha", "reportCorrectRecaptcha", "reportIncorrectHcaptcha":
+		if _, ok := s.tasks[intValue(payload["taskId"])]; !ok {
+			return errorResponse("ERROR_NO_SUCH_CAPCHA_ID")
+		}
+		return success(map[string]interface{}{"status": "success"})
+	case "pushAntiGateVariable":
+		if _, ok := s.tasks[intValue(payload["taskId"])]; !ok {
+			return errorResponse("ERROR_NO_SUCH_CAPCHA_ID")
+		}
+		return success(map[string]interface{}{"status": "success"})
+	case "getQueueStats":
+		// The real API replies to getQueueStats without an errorId.
+		return map[string]interface{}{
+			"waiting": 10,
+			"load":    50.0,
+			"bid":     0.0005,
+			"speed":   10.0,
+			"total":   100,
+		}
+	case "getSpendingStats":
+		return success(map[string]interface{}{"data": []interface{}{}})
+	case "getAppStats":
+		return success(map[string]interface{}{"chartData": []interface{}{}})
+	}
+	return errorResponse("ERROR_NO_SUCH_METHOD")
+}
+
+func (s *Server) createTask(payload map[string]interface{}) map[string]interface{} {
+	taskPayload, ok := payload["task"].(map[string]interface{})
+	if !ok {
+		return errorResponse("ERROR_TASK_ABSENT")
+	}
+	if s.balance <= 0 {
+		return errorResponse("ERROR_ZERO_BALANCE")
+	}
+	s.lastTaskID++
+	s.tasks[s.lastTaskID] = &task{
+		payload:    taskPayload,
+		rounds:     s.processingRounds,
+		createTime: time.Now(),
+	}
+	s.tasks[s.lastTaskID].callbackURL, _ = payload["callbackUrl"].(string)
+	return success(map[string]interface{}{"taskId": s.lastTaskID})
+}
+
+func (s *Server) getTaskResult(payload map[string]interface{}) map[string]interface{} {
+	t, ok := s.tasks[intValue(payload["taskId"])]
+	if !ok {
+		return errorResponse("ERROR_NO_SUCH_CAPCHA_ID")
+	}
+	if t.rounds > 0 {
+		t.rounds--
+		return success(map[string]interface{}{"status": "processing"})
+	}
+	if t.endTime.IsZero() {
+		t.endTime = time.Now()
+		s.balance -= s.cost
+	}
+	taskType, _ := t.payload["type"].(string)
+	solution, ok := s.solutions[taskType]
+	if !ok {
+		solution = defaultSolution
+	}
+	return success(map[string]interface{}{
+		"status":     "ready",
+		"solution":   solution,
+		"cost":       strconv.FormatFloat(s.cost, 'f', -1, 64),
+		"ip":         "127.0.0.1",
+		"createTime": t.createTime.Unix(),
+		"endTime":    t.endTime.Unix(),
+		"solveCount": 0,
+	})
+}
+
+func success(fields map[string]interface{}) map[string]interface{} {
+	response := map[string]interface{}{"errorId": 0}
+	for key, value := range fields {
+		response[key] = value
+	}
+	return response
+}
+
+func errorResponse(code string) map[string]interface{} {
+	id, ok := errorIDs[code]
+	if !ok {
+		id = 1
+	}
+	return map[string]interface{}{
+		"errorId":          id,
+		"errorCode":        code,
+		"errorDescription": "anticaptchatest: " + code,
+	}
+}
+
+func intValue(value interface{}) int {
+	number, _ := value.(float64)
+	return int(number)
+}
@@ -0,0 +1,237 @@
+package anticaptchatest_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+var imageTask = map[string]interface{}{
+	"type": "ImageToTextTask",
+	"body": "aW1hZ2U=",
+}
+
+func TestServerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(*anticaptchatest.Server, *anticaptcha.Client)
+		call  func(context.Context, *anticaptcha.Client) error
+		want  error
+	}{
+		{
+			name:  "unknown key",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) { ac.ClientKey = "unknown" },
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				_, err := ac.GetBalanceContext(ctx)
+				return err
+			},
+			want: anticaptcha.ErrKeyDoesNotExist,
+		},
+		{
+			name: "injected error",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) {
+				s.InjectError("createTask", "ERROR_NO_SLOT_AVAILABLE")
+			},
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				_, err := ac.Solve(ctx, imageTask)
+				return err
+			},
+			want: anticaptcha.ErrNoSlotAvailable,
+		},
+		{
+			name: "injected errors are consumed",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) {
+				s.InjectError("createTask", "ERROR_NO_SLOT_AVAILABLE")
+				ac.CreateTask(context.Background(), imageTask)
+			},
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				_, err := ac.Solve(ctx, imageTask)
+				return err
+			},
+		},
+		{
+			name:  "zero balance",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) { s.SetBalance(0) },
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				_, err := ac.Solve(ctx, imageTask)
+				return err
+			},
+			want: anticaptcha.ErrZeroBalance,
+		},
+		{
+			name: "unsolvable task",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) {
+				s.InjectError("getTaskResult", "ERROR_CAPTCHA_UNSOLVABLE")
+			},
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				_, err := ac.Solve(ctx, imageTask)
+				return err
+			},
+			want: anticaptcha.ErrCaptchaUnsolvable,
+		},
+		{
+			name: "report unknown task",
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				return ac.ReportIncorrectImageCaptchaByID(ctx, 404)
+			},
+			want: anticaptcha.ErrNoSuchCaptchaID,
+		},
+		{
+			name: "push variable to unknown task",
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				return ac.AntiGateTaskByID(404).PushVariable(ctx, "otp", "123456")
+			},
+			want: anticaptcha.ErrNoSuchCaptchaID,
+		},
+		{
+			name:  "latency",
+			setup: func(s *anticaptchatest.Server, ac *anticaptcha.Client) { s.SetLatency(time.Second) },
+			call: func(ctx context.Context, ac *anticaptcha.Client) error {
+				ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+				_, err := ac.GetBalanceContext(ctx)
+				return err
+			},
+			want: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			ac := srv.Client()
+			if tt.setup != nil {
+				tt.setup(srv, ac)
+			}
+			err := tt.call(context.Background(), ac)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerSolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounds   int
+		solution map[string]interface{}
+		wantText string
+	}{
+		{name: "default solution", wantText: "anticaptchatest"},
+		{name: "canned solution", solution: map[string]interface{}{"text": "deditur"}, wantText: "deditur"},
+		{name: "processing rounds", rounds: 3, wantText: "anticaptchatest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			srv.SetCost(0.5)
+			srv.SetProcessingRounds(tt.rounds)
+			if tt.solution != nil {
+				srv.SetSolution("ImageToTextTask", tt.solution)
+			}
+			ac := srv.Client()
+			ctx := context.Background()
+
+			result, err := ac.Solve(ctx, imageTask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text := result.Solution["text"]; text != tt.wantText {
+				t.Errorf("got text %v, want %q", text, tt.wantText)
+			}
+			if result.Cost != 0.5 {
+				t.Errorf("got cost %v, want 0.5", result.Cost)
+			}
+			if polls := len(srv.Requests("getTaskResult")); polls != tt.rounds+1 {
+				t.Errorf("got %d polls, want %d", polls, tt.rounds+1)
+			}
+			if tasks := srv.Tasks(); len(tasks) != 1 || tasks[0]["body"] != imageTask["body"] {
+				t.Errorf("got tasks %v", tasks)
+			}
+
+			// Polling a solved task again must not charge it twice.
+			if _, _, err := ac.TaskByID(result.TaskID).Poll(ctx); err != nil {
+				t.Fatal(err)
+			}
+			balance, err := ac.GetBalanceContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if balance != 9.5 {
+				t.Errorf("got balance %v, want 9.5", balance)
+			}
+		})
+	}
+}
+
+type countingLogger struct {
+	mu     sync.Mutex
+	events int
+}
+
+func (l *countingLogger) Log(ctx context.Context, level anticaptcha.LogLevel, msg string, fields ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events++
+}
+
+func TestServerClientKeepsLogger(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	logger := &countingLogger{}
+	ac := srv.Client(anticaptcha.WithLogger(logger))
+
+	if _, err := ac.Solve(context.Background(), imageTask); err != nil {
+		t.Fatal(err)
+	}
+	if logger.events == 0 {
+		t.Error("the logger passed to Client received no events")
+	}
+}
+
+func TestServerQueueStats(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	ac := srv.Client()
+
+	stats, err := ac.GetQueueStats(context.Background(), anticaptcha.QueueRecaptchaV2Proxyless)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Waiting != 10 || stats.Total != 100 {
+		t.Errorf("got %+v", stats)
+	}
+
+	// Errors still carry an errorId, as on the real API.
+	srv.InjectError("getQueueStats", "ERROR_NO_SUCH_METHOD")
+	var apiErr *anticaptcha.APIError
+	if _, err := ac.GetQueueStats(context.Background(), anticaptcha.QueueRecaptchaV2Proxyless); !errors.As(err, &apiErr) {
+		t.Errorf("got %v, want an *APIError", err)
+	}
+}
+
+func TestServerCannedResponse(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	srv.SetResponse("getQueueStats", map[string]interface{}{"waiting": 3, "load": 97.5, "bid": "0.0011"})
+	ac := srv.Client()
+
+	stats, err := ac.GetQueueStats(context.Background(), anticaptcha.QueueImageEnglish)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Waiting != 3 || stats.Load != 97.5 || stats.Bid != 0.0011 {
+		t.Errorf("got %+v", stats)
+	}
+	requests := srv.Requests("getQueueStats")
+	if len(requests) != 1 || requests[0].Payload["queueId"] != float64(anticaptcha.QueueImageEnglish) {
+		t.Errorf("got requests %v", requests)
+	}
+}
@@ -0,0 +1,57 @@
+package anticaptcha
+
+import "context"
+
+// AntiGateTask is a running AntiGate task. Templates which pause for a value
+// that is only known later, like a 2FA code, continue once it is pushed with
+// PushVariable.
+type AntiGateTask struct {
+	*Task
+	proxy *Proxy
+}
+
+// StartAntiGate creates an AntiGate task without waiting for its result.
+func (ac *Client) StartAntiGate(ctx context.Context, antigate AntiGate) (*AntiGateTask, error) {
+	proxy, err := ac.optionalProxy(antigate.Proxy, antigate.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	antigate.Proxy = proxy
+	task, err := ac.CreateTask(ctx, antiGateTask(antigate))
+	if err != nil {
+		ac.reportProxy(proxy, err)
+		return nil, err
+	}
+	return &AntiGateTask{Task: task, proxy: proxy}, nil
+}
+
+// AntiGateTaskByID returns a handle to a running AntiGate task created with
+// the Client's own endpoint and key.
+func (ac *Client) AntiGateTaskByID(taskID int) *AntiGateTask {
+	task := ac.TaskByID(taskID)
+	task.taskType = "AntiGateTask"
+	return &AntiGateTask{Task: task}
+}
+
+// PushVariable sets the template variable name while the task is running.
+func (t *AntiGateTask) PushVariable(ctx context.Context, name string, value interface{}) error {
+	_, err := t.client.request(ctx, t.provider, "pushAntiGateVariable", map[string]interface{}{
+		"clientKey": t.provider.ClientKey,
+		"taskId":    t.id,
+		"name":      name,
+		"value":     value,
+	})
+	return err
+}
+
+// WaitResult waits for the task like Wait and returns a typed solution.
+func (t *AntiGateTask) WaitResult(ctx context.Context) (*AntiGateSolution, error) {
+	result, err := t.Wait(ctx)
+	if t.proxy != nil {
+		t.client.reportProxy(t.proxy, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newAntiGateSolution(result), nil
+}
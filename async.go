@@ -0,0 +1,280 @@
+package anticaptcha
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Task is a handle to a task created with CreateTask. A handle can be rebuilt
+// from a stored task ID with TaskByID, for example in another process.
+type Task struct {
+	client     *Client
+	provider   Provider
+	id         int
+	taskType   string
+	websiteURL string
+	created    time.Time
+	callback   bool
+	recordCost sync.Once
+}
+
+// CreateTask submits the task and returns without waiting for the solution.
+// With several Providers the task goes to the first one which accepts it;
+// the Client's FailoverPolicy decides which errors skip to the next provider.
+// With a Budget the task is refused with a *BudgetError once a limit is reached.
+func (ac *Client) CreateTask(ctx context.Context, task map[string]interface{}) (*Task, error) {
+	websiteURL, _ := task["websiteURL"].(string)
+	if ac.Budget != nil {
+		if err := ac.Budget.Check(websiteURL); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	for _, provider := range ac.providers() {
+		var t *Task
+		t, err = ac.createTaskWithKeys(ctx, provider, task)
+		if err == nil || ctx.Err() != nil || !ac.failsOver(err) {
+			return t, err
+		}
+		ac.logger().Log(ctx, LogLevelWarn, "provider failed",
+			"provider", provider.Name,
+			"task_type", task["type"],
+			"error", err,
+		)
+	}
+	return nil, err
+}
+
+// createTaskWithKeys picks the client key from KeyPool, if one is set and no
+// Providers are configured, and moves on to the next key when the picked one
+// has no balance or does not exist.
+func (ac *Client) createTaskWithKeys(ctx context.Context, provider Provider, task map[string]interface{}) (*Task, error) {
+	if ac.KeyPool == nil || len(ac.Providers) > 0 {
+		return ac.createTask(ctx, provider, task)
+	}
+	for {
+		key, err := ac.KeyPool.Pick()
+		if err != nil {
+			return nil, err
+		}
+		provider.ClientKey = key
+		t, err := ac.createTask(ctx, provider, task)
+		if err == nil || !disablesKey(err) {
+			return t, err
+		}
+		ac.KeyPool.Disable(key, err)
+		ac.logger().Log(ctx, LogLevelWarn, "key taken out of rotation",
+			"error", err,
+		)
+	}
+}
+
+func (ac *Client) createTask(ctx context.Context, provider Provider, task map[string]interface{}) (*Task, error) {
+	payload := map[string]interface{}{
+		"clientKey": provider.ClientKey,
+		"task":      task,
+		"softId":    ac.SoftId,
+	}
+	if ac.CallbackURL != "" {
+		payload["callbackUrl"] = ac.CallbackURL
+	}
+	created := time.Now()
+	taskCreateResult, err := ac.request(ctx, provider, "createTask", payload)
+	if err != nil {
+		return nil, err
+	}
+	taskID, ok := taskCreateResult["taskId"].(float64)
+	if !ok {
+		return nil, ErrInvalidResponse
+	}
+	t := &Task{
+		client:   ac,
+		provider: provider,
+		id:       int(taskID),
+		created:  created,
+		callback: ac.CallbackURL != "",
+	}
+	t.taskType, _ = task["type"].(string)
+	t.websiteURL, _ = task["websiteURL"].(string)
+	ac.logger().Log(ctx, LogLevelInfo, "task created",
+		"task_id", t.id,
+		"task_type", t.taskType,
+		"provider", provider.Name,
+	)
+	return t, nil
+}
+
+// TaskByID returns a handle to an existing task created with the Client's
+// own endpoint and key.
+func (ac *Client) TaskByID(taskID int) *Task {
+	return &Task{
+		client:   ac,
+		provider: ac.defaultProvider(),
+		id:       taskID,
+	}
+}
+
+// ProviderTaskByID returns a handle to an existing task created with the
+// named provider from Providers.
+func (ac *Client) ProviderTaskByID(providerName string, taskID int) (*Task, error) {
+	provider, ok := ac.provider(providerName)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return &Task{
+		client:   ac,
+		provider: provider,
+		id:       taskID,
+	}, nil
+}
+
+func (t *Task) ID() int {
+	return t.id
+}
+
+// TaskByKey returns a handle to an existing task created with the Client's
+// own endpoint and the given key, for example one picked from a KeyPool.
+func (ac *Client) TaskByKey(clientKey string, taskID int) *Task {
+	task := ac.TaskByID(taskID)
+	task.provider.ClientKey = clientKey
+	return task
+}
+
+// TaskByMeta returns a handle to the task behind a solution, with the
+// provider and key it was created with.
+func (ac *Client) TaskByMeta(meta TaskMeta) (*Task, error) {
+	provider, err := ac.metaProvider(meta)
+	if err != nil {
+		return nil, err
+	}
+	return &Task{
+		client:   ac,
+		provider: provider,
+		id:       meta.TaskID,
+		taskType: meta.TaskType,
+	}, nil
+}
+
+// ClientKey returns the key the task was created with.
+func (t *Task) ClientKey() string {
+	return t.provider.ClientKey
+}
+
+// Provider returns the name of the provider the task was created with.
+func (t *Task) Provider() string {
+	return t.provider.Name
+}
+
+// Poll makes a single getTaskResult call. It returns a nil result and false
+// while the task is still being processed.
+func (t *Task) Poll(ctx context.Context) (*TaskResult, bool, error) {
+	response, err := t.client.request(ctx, t.provider, "getTaskResult", map[string]interface{}{
+		"clientKey": t.provider.ClientKey,
+		"taskId":    t.id,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if status, _ := response["status"].(string); status != "ready" {
+		return nil, false, nil
+	}
+	return t.result(response), true, nil
+}
+
+// result builds the TaskResult of a ready getTaskResult response or callback.
+func (t *Task) result(response map[string]interface{}) *TaskResult {
+	result := &TaskResult{
+		TaskMeta: TaskMeta{
+			TaskID:     t.id,
+			TaskType:   t.taskType,
+			Provider:   t.provider.Name,
+			KeyID:      KeyID(t.provider.ClientKey),
+			Cost:       floatValue(response["cost"]),
+			CreateTime: unixTime(response["createTime"]),
+			EndTime:    unixTime(response["endTime"]),
+			SolveCount: int(floatValue(response["solveCount"])),
+		},
+	}
+	result.IP, _ = response["ip"].(string)
+	if !result.CreateTime.IsZero() && !result.EndTime.IsZero() {
+		result.SolveTime = result.EndTime.Sub(result.CreateTime)
+	} else if !t.created.IsZero() {
+		result.SolveTime = time.Since(t.created)
+	}
+	result.Solution, _ = response["solution"].(map[string]interface{})
+	if t.client.Budget != nil {
+		t.recordCost.Do(func() {
+			t.client.Budget.Record(t.websiteURL, result.Cost)
+		})
+	}
+	return result
+}
+
+// Wait polls the task until it is solved, fails or ctx is done. The first
+// poll happens FirstAttemptWaitingInterval seconds after the task was created.
+func (t *Task) Wait(ctx context.Context) (*TaskResult, error) {
+	delay := time.Duration(t.client.FirstAttemptWaitingInterval) * time.Second
+	if !t.created.IsZero() {
+		delay -= time.Since(t.created)
+	} else {
+		delay = 0
+	}
+	return t.wait(ctx, delay)
+}
+
+func (t *Task) wait(ctx context.Context, delay time.Duration) (*TaskResult, error) {
+	if t.id <= 0 {
+		return nil, ErrNoSlotAvailable
+	}
+	ac := t.client
+	logger := ac.logger()
+	started := time.Now()
+	if t.callback && ac.Callbacks != nil {
+		result, ok, err := t.waitCallback(ctx)
+		if ok || err != nil {
+			return result, err
+		}
+		logger.Log(ctx, LogLevelWarn, "no callback received, polling",
+			"task_id", t.id,
+			"elapsed", time.Since(started),
+		)
+		delay = 0
+	}
+	logger.Log(ctx, LogLevelDebug, "waiting for first poll",
+		"task_id", t.id,
+		"interval", delay,
+	)
+	if err := sleepContext(ctx, delay); err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		logger.Log(ctx, LogLevelDebug, "poll attempt",
+			"task_id", t.id,
+			"task_type", t.taskType,
+			"attempt", attempt,
+			"elapsed", time.Since(started),
+		)
+		result, ready, err := t.Poll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ready {
+			logger.Log(ctx, LogLevelInfo, "solved",
+				"task_id", t.id,
+				"task_type", t.taskType,
+				"attempt", attempt,
+				"elapsed", time.Since(started),
+			)
+			return result, nil
+		}
+		logger.Log(ctx, LogLevelDebug, "captcha result is not yet ready",
+			"task_id", t.id,
+			"interval", time.Duration(ac.NormalWaitingInterval)*time.Second,
+		)
+		if err := sleepContext(ctx, time.Duration(ac.NormalWaitingInterval)*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
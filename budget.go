@@ -0,0 +1,198 @@
+package anticaptcha
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded matches every *BudgetError with errors.Is.
+var ErrBudgetExceeded = errors.New("spending budget exceeded")
+
+// BudgetError is returned by CreateTask when a spending limit is reached.
+// WebsiteURL is the site of the limit, or empty for client-wide limits, and
+// Window is zero for limits without a time window.
+type BudgetError struct {
+	WebsiteURL string
+	Window     time.Duration
+	Limit      float64
+	Spent      float64
+}
+
+func (e *BudgetError) Error() string {
+	scope := "client"
+	if e.WebsiteURL != "" {
+		scope = e.WebsiteURL
+	}
+	if e.Window > 0 {
+		return fmt.Sprintf("spending budget exceeded for %s: spent %.4f of %.4f per %s", scope, e.Spent, e.Limit, e.Window)
+	}
+	return fmt.Sprintf("spending budget exceeded for %s: spent %.4f of %.4f", scope, e.Spent, e.Limit)
+}
+
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// Budget caps spending by the cost reported in getTaskResult. Costs are
+// counted when results arrive, so tasks already in flight can overshoot a
+// limit by their own cost. A Budget is safe for concurrent use and may be
+// shared by several clients.
+//
+// Per-site limits and spending are kept by the scheme and host of a
+// WebsiteURL, so a limit on "https://example.com" covers every page of it.
+type Budget struct {
+	mu     sync.Mutex
+	limits map[budgetKey]float64
+	totals map[string]float64
+	spends []budgetSpend
+}
+
+type budgetKey struct {
+	websiteURL string
+	window     time.Duration
+}
+
+type budgetSpend struct {
+	websiteURL string
+	cost       float64
+	at         time.Time
+}
+
+func NewBudget() *Budget {
+	return &Budget{
+		limits: make(map[budgetKey]float64),
+		totals: make(map[string]float64),
+	}
+}
+
+// SetLimit caps spending on websiteURL within a rolling window. An empty
+// websiteURL caps the whole client and a zero window caps all time spending.
+// A zero amount removes the limit.
+func (b *Budget) SetLimit(websiteURL string, window time.Duration, amount float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := budgetKey{websiteURL: budgetSite(websiteURL), window: window}
+	if amount <= 0 {
+		delete(b.limits, key)
+		return
+	}
+	b.limits[key] = amount
+}
+
+// Record adds the cost of a solved task.
+func (b *Budget) Record(websiteURL string, cost float64) {
+	if cost <= 0 {
+		return
+	}
+	websiteURL = budgetSite(websiteURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.totals[""] += cost
+	if websiteURL != "" {
+		b.totals[websiteURL] += cost
+	}
+	b.spends = append(b.spends, budgetSpend{websiteURL: websiteURL, cost: cost, at: now})
+	b.prune(now)
+}
+
+// Spent returns how much was spent on websiteURL, or by the whole client for
+// an empty websiteURL, within window. A zero window returns all time spending.
+func (b *Budget) Spent(websiteURL string, window time.Duration) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent(budgetSite(websiteURL), window, time.Now())
+}
+
+// Remaining returns the budget left for a task on websiteURL under the
+// tightest applicable limit, or +Inf if no limit applies.
+func (b *Budget) Remaining(websiteURL string) float64 {
+	websiteURL = budgetSite(websiteURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	remaining := math.Inf(1)
+	for key, limit := range b.limits {
+		if !key.applies(websiteURL) {
+			continue
+		}
+		left := limit - b.spent(key.websiteURL, key.window, now)
+		if left < 0 {
+			left = 0
+		}
+		remaining = math.Min(remaining, left)
+	}
+	return remaining
+}
+
+// Check returns a *BudgetError if a limit applicable to websiteURL is reached.
+func (b *Budget) Check(websiteURL string) error {
+	websiteURL = budgetSite(websiteURL)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for key, limit := range b.limits {
+		if !key.applies(websiteURL) {
+			continue
+		}
+		if spent := b.spent(key.websiteURL, key.window, now); spent >= limit {
+			return &BudgetError{
+				WebsiteURL: key.websiteURL,
+				Window:     key.window,
+				Limit:      limit,
+				Spent:      spent,
+			}
+		}
+	}
+	return nil
+}
+
+// budgetSite reduces websiteURL to its scheme and host. Values which are not
+// absolute URLs are kept as they are.
+func budgetSite(websiteURL string) string {
+	u, err := url.Parse(websiteURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return websiteURL
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+func (k budgetKey) applies(websiteURL string) bool {
+	return k.websiteURL == "" || k.websiteURL == websiteURL
+}
+
+func (b *Budget) spent(websiteURL string, window time.Duration, now time.Time) float64 {
+	if window <= 0 {
+		return b.totals[websiteURL]
+	}
+	since := now.Add(-window)
+	var spent float64
+	for _, s := range b.spends {
+		if s.at.After(since) && (websiteURL == "" || s.websiteURL == websiteURL) {
+			spent += s.cost
+		}
+	}
+	return spent
+}
+
+// prune drops spends older than the longest window, keeping at least a day
+// so that limits added later start with some history.
+func (b *Budget) prune(now time.Time) {
+	longest := 24 * time.Hour
+	for key := range b.limits {
+		if key.window > longest {
+			longest = key.window
+		}
+	}
+	since := now.Add(-longest)
+	i := 0
+	for i < len(b.spends) && !b.spends[i].at.After(since) {
+		i++
+	}
+	b.spends = append(b.spends[:0], b.spends[i:]...)
+}
@@ -0,0 +1,234 @@
+package anticaptcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testSpend struct {
+	site string
+	cost float64
+	ago  time.Duration
+}
+
+type testLimit struct {
+	site   string
+	window time.Duration
+	amount float64
+}
+
+func newTestBudget(limits []testLimit, spends []testSpend) *Budget {
+	b := NewBudget()
+	for _, l := range limits {
+		b.SetLimit(l.site, l.window, l.amount)
+	}
+	now := time.Now()
+	for _, s := range spends {
+		site := budgetSite(s.site)
+		b.totals[""] += s.cost
+		if site != "" {
+			b.totals[site] += s.cost
+		}
+		b.spends = append(b.spends, budgetSpend{websiteURL: site, cost: s.cost, at: now.Add(-s.ago)})
+	}
+	return b
+}
+
+func TestBudgetCheckAndRemaining(t *testing.T) {
+	const site = "https://example.com/"
+	tests := []struct {
+		name          string
+		limits        []testLimit
+		spends        []testSpend
+		websiteURL    string
+		wantExceeded  bool
+		wantRemaining float64
+	}{
+		{
+			name:          "no limits",
+			spends:        []testSpend{{site: site, cost: 100}},
+			websiteURL:    site,
+			wantRemaining: math.Inf(1),
+		},
+		{
+			name:          "all time client limit",
+			limits:        []testLimit{{amount: 10}},
+			spends:        []testSpend{{site: site, cost: 4, ago: 48 * time.Hour}, {cost: 3}},
+			websiteURL:    site,
+			wantRemaining: 3,
+		},
+		{
+			name:         "all time client limit reached",
+			limits:       []testLimit{{amount: 10}},
+			spends:       []testSpend{{cost: 6, ago: 48 * time.Hour}, {cost: 4}},
+			wantExceeded: true,
+		},
+		{
+			name:          "old spends leave the window",
+			limits:        []testLimit{{window: time.Hour, amount: 5}},
+			spends:        []testSpend{{cost: 4, ago: 2 * time.Hour}, {cost: 1, ago: time.Minute}},
+			wantRemaining: 4,
+		},
+		{
+			name:         "window limit reached",
+			limits:       []testLimit{{window: time.Hour, amount: 5}},
+			spends:       []testSpend{{cost: 3, ago: 30 * time.Minute}, {cost: 2, ago: time.Minute}},
+			wantExceeded: true,
+		},
+		{
+			name:          "site limit ignores other sites",
+			limits:        []testLimit{{site: site, window: time.Hour, amount: 2}},
+			spends:        []testSpend{{site: "https://other.example/", cost: 5}, {site: site, cost: 0.5}},
+			websiteURL:    site,
+			wantRemaining: 1.5,
+		},
+		{
+			name:          "site limit does not apply to other sites",
+			limits:        []testLimit{{site: site, amount: 2}},
+			spends:        []testSpend{{site: site, cost: 2}},
+			websiteURL:    "https://other.example/",
+			wantRemaining: math.Inf(1),
+		},
+		{
+			name:          "site limit covers every page",
+			limits:        []testLimit{{site: "https://example.com", window: time.Hour, amount: 2}},
+			spends:        []testSpend{{site: "https://example.com/login", cost: 0.5}, {site: "https://EXAMPLE.com/a/b?c=d", cost: 0.5}},
+			websiteURL:    "https://example.com/checkout",
+			wantRemaining: 1,
+		},
+		{
+			name:          "site limit is per scheme and host",
+			limits:        []testLimit{{site: "https://example.com", amount: 2}},
+			spends:        []testSpend{{site: "https://example.com/", cost: 2}},
+			websiteURL:    "https://sub.example.com/",
+			wantRemaining: math.Inf(1),
+		},
+		{
+			name:         "site limit reached",
+			limits:       []testLimit{{site: site, amount: 2}},
+			spends:       []testSpend{{site: site, cost: 2}},
+			websiteURL:   site,
+			wantExceeded: true,
+		},
+		{
+			name: "tightest limit wins",
+			limits: []testLimit{
+				{amount: 100},
+				{window: 24 * time.Hour, amount: 10},
+				{site: site, window: time.Hour, amount: 3},
+			},
+			spends:        []testSpend{{site: site, cost: 2, ago: 2 * time.Hour}, {site: site, cost: 1}},
+			websiteURL:    site,
+			wantRemaining: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBudget(tt.limits, tt.spends)
+			err := b.Check(tt.websiteURL)
+			if exceeded := errors.Is(err, ErrBudgetExceeded); exceeded != tt.wantExceeded {
+				t.Fatalf("Check returned %v, want exceeded=%v", err, tt.wantExceeded)
+			}
+			var budgetErr *BudgetError
+			if tt.wantExceeded && !errors.As(err, &budgetErr) {
+				t.Fatalf("Check returned %T, want *BudgetError", err)
+			}
+			remaining := b.Remaining(tt.websiteURL)
+			if tt.wantExceeded {
+				tt.wantRemaining = 0
+			}
+			if math.Abs(remaining-tt.wantRemaining) > 1e-9 && !(math.IsInf(remaining, 1) && math.IsInf(tt.wantRemaining, 1)) {
+				t.Errorf("Remaining = %v, want %v", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestBudgetPrune(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits []testLimit
+		spends []testSpend
+		want   int
+	}{
+		{
+			name:   "keeps a day without window limits",
+			spends: []testSpend{{cost: 1, ago: 25 * time.Hour}, {cost: 1, ago: 23 * time.Hour}},
+			want:   2,
+		},
+		{
+			name:   "keeps the longest window",
+			limits: []testLimit{{window: time.Hour, amount: 10}, {window: 72 * time.Hour, amount: 10}},
+			spends: []testSpend{{cost: 1, ago: 73 * time.Hour}, {cost: 1, ago: 48 * time.Hour}},
+			want:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBudget(tt.limits, tt.spends)
+			b.Record("", 1)
+			if got := len(b.spends); got != tt.want {
+				t.Errorf("kept %d spends, want %d", got, tt.want)
+			}
+			if got := b.Spent("", 0); got != 3 {
+				t.Errorf("all time spending is %v after pruning, want 3", got)
+			}
+		})
+	}
+}
+
+func TestBudgetRefusesTasks(t *testing.T) {
+	var created int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := map[string]interface{}{"errorId": 0}
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case "createTask":
+			response["taskId"] = atomic.AddInt32(&created, 1)
+		case "getTaskResult":
+			response["status"] = "ready"
+			response["solution"] = map[string]interface{}{"gRecaptchaResponse": "token"}
+			response["cost"] = "0.002"
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer srv.Close()
+
+	budget := NewBudget()
+	budget.SetLimit("https://example.com", 0, 0.005)
+	ac := NewClient("key", WithBaseURL(srv.URL), WithBudget(budget))
+	ac.FirstAttemptWaitingInterval = 0
+	ac.ShutUp()
+	task := func(websiteURL string) map[string]interface{} {
+		return map[string]interface{}{"type": "RecaptchaV2TaskProxyless", "websiteURL": websiteURL, "websiteKey": "key"}
+	}
+	ctx := context.Background()
+
+	// The third task starts with 0.004 spent and takes the site over its limit.
+	for i := 0; i < 3; i++ {
+		if _, err := ac.Solve(ctx, task("https://example.com/login")); err != nil {
+			t.Fatalf("task %d: %v", i+1, err)
+		}
+	}
+	_, err := ac.CreateTask(ctx, task("https://example.com/checkout"))
+	var budgetErr *BudgetError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("got %v, want a *BudgetError", err)
+	}
+	if budgetErr.WebsiteURL != "https://example.com" || math.Abs(budgetErr.Spent-0.006) > 1e-9 {
+		t.Errorf("got %+v", budgetErr)
+	}
+	if n := atomic.LoadInt32(&created); n != 3 {
+		t.Errorf("sent %d createTask requests, want 3", n)
+	}
+	if _, err := ac.Solve(ctx, task("https://other.example/")); err != nil {
+		t.Errorf("another site was refused: %v", err)
+	}
+}
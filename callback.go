@@ -0,0 +1,185 @@
+package anticaptcha
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// defaultCallbackTimeout is used when CallbackTimeout is not set.
+const defaultCallbackTimeout = 5 * time.Minute
+
+// callbackRetention is how long a callback nobody waits for yet is kept.
+const callbackRetention = 10 * time.Minute
+
+// maxEarlyCallbacks bounds the callbacks kept for tasks nobody waits for yet.
+const maxEarlyCallbacks = 1000
+
+// maxCallbackSize bounds the size of a callback body.
+const maxCallbackSize = 256 << 10
+
+// CallbackHandler receives the results the API posts to a callbackUrl and
+// hands each one to the task waiting for it. Results which arrive before
+// anyone waits for them are kept for a while, so they are not lost when a
+// task is solved quickly.
+//
+// Anyone who can reach the handler can post results to it, so set a secret
+// with SetSecret when it is exposed publicly.
+type CallbackHandler struct {
+	mu      sync.Mutex
+	secret  string
+	waiters map[int]chan map[string]interface{}
+	early   map[int]earlyCallback
+}
+
+type earlyCallback struct {
+	response map[string]interface{}
+	received time.Time
+}
+
+func NewCallbackHandler() *CallbackHandler {
+	return &CallbackHandler{
+		waiters: make(map[int]chan map[string]interface{}),
+		early:   make(map[int]earlyCallback),
+	}
+}
+
+// SetSecret makes the handler reject callbacks whose URL lacks secret in the
+// "secret" query parameter. CallbackURL adds it to the URL given to
+// WithCallbacks.
+func (h *CallbackHandler) SetSecret(secret string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.secret = secret
+}
+
+// CallbackURL returns baseURL with the handler's secret added.
+func (h *CallbackHandler) CallbackURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	h.mu.Lock()
+	secret := h.secret
+	h.mu.Unlock()
+	if secret != "" {
+		query := u.Query()
+		query.Set("secret", secret)
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mu.Lock()
+	secret := h.secret
+	h.mu.Unlock()
+	if secret != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("secret")), []byte(secret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	var response map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCallbackSize)).Decode(&response); err != nil {
+		http.Error(w, "invalid callback", http.StatusBadRequest)
+		return
+	}
+	taskID := int(floatValue(response["taskId"]))
+	if taskID <= 0 {
+		http.Error(w, "missing taskId", http.StatusBadRequest)
+		return
+	}
+	if !h.deliver(taskID, response) {
+		http.Error(w, "too many pending callbacks", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// deliver hands the callback to its waiter or keeps it for later. It returns
+// false when too many callbacks are kept already.
+func (h *CallbackHandler) deliver(taskID int, response map[string]interface{}) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if waiter, ok := h.waiters[taskID]; ok {
+		delete(h.waiters, taskID)
+		waiter <- response
+		return true
+	}
+	now := time.Now()
+	for id, callback := range h.early {
+		if now.Sub(callback.received) > callbackRetention {
+			delete(h.early, id)
+		}
+	}
+	if _, ok := h.early[taskID]; !ok && len(h.early) >= maxEarlyCallbacks {
+		return false
+	}
+	h.early[taskID] = earlyCallback{response: response, received: now}
+	return true
+}
+
+// subscribe returns a channel which receives the callback of the task, and a
+// function to stop waiting for it.
+func (h *CallbackHandler) subscribe(taskID int) (<-chan map[string]interface{}, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	waiter := make(chan map[string]interface{}, 1)
+	if callback, ok := h.early[taskID]; ok {
+		delete(h.early, taskID)
+		waiter <- callback.response
+		return waiter, func() {}
+	}
+	h.waiters[taskID] = waiter
+	return waiter, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.waiters[taskID] == waiter {
+			delete(h.waiters, taskID)
+		}
+	}
+}
+
+// waitCallback waits for the task's callback until CallbackTimeout. It
+// returns false without an error when no usable callback arrived, so the
+// caller can fall back to polling.
+func (t *Task) waitCallback(ctx context.Context) (*TaskResult, bool, error) {
+	ac := t.client
+	callbacks, stop := ac.Callbacks.subscribe(t.id)
+	defer stop()
+
+	timeout := ac.CallbackTimeout
+	if timeout <= 0 {
+		timeout = defaultCallbackTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case <-timer.C:
+		return nil, false, nil
+	case response := <-callbacks:
+		if floatValue(response["errorId"]) > 0 {
+			return nil, false, newAPIError(response, "getTaskResult", t.id)
+		}
+		if status, _ := response["status"].(string); status != "ready" {
+			return nil, false, nil
+		}
+		ac.logger().Log(ctx, LogLevelInfo, "solved",
+			"task_id", t.id,
+			"task_type", t.taskType,
+			"callback", true,
+		)
+		return t.result(response), true, nil
+	}
+}
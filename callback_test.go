@@ -0,0 +1,183 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+// newCallbackClient returns a client which asks srv to post results to a
+// CallbackHandler served by an httptest server.
+func newCallbackClient(t *testing.T, srv *anticaptchatest.Server, timeout time.Duration) *anticaptcha.Client {
+	handler := anticaptcha.NewCallbackHandler()
+	handler.SetSecret("s3cret")
+	callbacks := httptest.NewServer(handler)
+	t.Cleanup(callbacks.Close)
+	callbackURL, err := handler.CallbackURL(callbacks.URL + "/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv.Client(anticaptcha.WithCallbacks(callbackURL, handler, timeout))
+}
+
+func TestCallbackDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		inject    string
+		post      bool
+		delay     time.Duration
+		timeout   time.Duration
+		wantErr   error
+		wantPolls bool
+	}{
+		{name: "before waiting", post: true, timeout: time.Minute},
+		{name: "while waiting", post: true, delay: 20 * time.Millisecond, timeout: time.Minute},
+		{name: "error callback", inject: "ERROR_CAPTCHA_UNSOLVABLE", post: true, timeout: time.Minute, wantErr: anticaptcha.ErrCaptchaUnsolvable},
+		{name: "timeout falls back to polling", timeout: 20 * time.Millisecond, wantPolls: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			ac := newCallbackClient(t, srv, tt.timeout)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			task, err := ac.CreateTask(ctx, imageTask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.inject != "" {
+				srv.InjectError("getTaskResult", tt.inject)
+			}
+			posted := make(chan struct{})
+			if tt.post && tt.delay == 0 {
+				postCallback(t, srv, task.ID())
+				close(posted)
+			} else {
+				go func() {
+					defer close(posted)
+					if tt.post {
+						time.Sleep(tt.delay)
+						postCallback(t, srv, task.ID())
+					}
+				}()
+			}
+			result, err := task.Wait(ctx)
+			<-posted
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if result.Solution["text"] != "anticaptchatest" {
+				t.Errorf("got solution %v", result.Solution)
+			}
+			if polls := len(srv.Requests("getTaskResult")); (polls > 0) != tt.wantPolls {
+				t.Errorf("got %d getTaskResult calls, want polling %v", polls, tt.wantPolls)
+			}
+		})
+	}
+}
+
+func postCallback(t *testing.T, srv *anticaptchatest.Server, id int) {
+	status, err := srv.PostCallback(id)
+	if err != nil {
+		t.Error(err)
+	} else if status != http.StatusOK {
+		t.Errorf("callback handler answered %d", status)
+	}
+}
+
+func TestCallbackOnlyForCallbackTasks(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	ac := newCallbackClient(t, srv, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task, err := ac.CreateTask(ctx, imageTask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A handle rebuilt from the ID polls instead of waiting for the callback.
+	if _, err := ac.TaskByID(task.ID()).Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallbackHandler(t *testing.T) {
+	handler := anticaptcha.NewCallbackHandler()
+	handler.SetSecret("s3cret")
+	callbacks := httptest.NewServer(handler)
+	defer callbacks.Close()
+
+	post := func(query, body string) int {
+		resp, err := http.Post(callbacks.URL+"/callback"+query, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	padded := func(size int) string {
+		return `{"taskId":1,"padding":"` + strings.Repeat("x", size) + `"}`
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		body  string
+		want  int
+	}{
+		{name: "valid", query: "?secret=s3cret", body: `{"taskId":1,"status":"ready"}`, want: http.StatusOK},
+		{name: "missing secret", body: `{"taskId":1,"status":"ready"}`, want: http.StatusForbidden},
+		{name: "wrong secret", query: "?secret=guess", body: `{"taskId":1,"status":"ready"}`, want: http.StatusForbidden},
+		{name: "missing taskId", query: "?secret=s3cret", body: `{"status":"ready"}`, want: http.StatusBadRequest},
+		{name: "not JSON", query: "?secret=s3cret", body: `<html>`, want: http.StatusBadRequest},
+		{name: "below size limit", query: "?secret=s3cret", body: padded(200 << 10), want: http.StatusOK},
+		{name: "above size limit", query: "?secret=s3cret", body: padded(256 << 10), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(tt.query, tt.body); got != tt.want {
+				t.Errorf("got status %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("GET", func(t *testing.T) {
+		resp, err := http.Get(callbacks.URL + "/callback?secret=s3cret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+		}
+	})
+
+	t.Run("early callback limit", func(t *testing.T) {
+		// Task 1 is already kept from the cases above.
+		for id := 2; id <= 1000; id++ {
+			if got := post("?secret=s3cret", `{"taskId":`+strconv.Itoa(id)+`}`); got != http.StatusOK {
+				t.Fatalf("callback %d: got status %d", id, got)
+			}
+		}
+		if got := post("?secret=s3cret", `{"taskId":1001}`); got != http.StatusServiceUnavailable {
+			t.Errorf("callback over the limit: got status %d, want %d", got, http.StatusServiceUnavailable)
+		}
+		if got := post("?secret=s3cret", `{"taskId":1000,"status":"ready"}`); got != http.StatusOK {
+			t.Errorf("repeated callback: got status %d, want %d", got, http.StatusOK)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+// TestClientConcurrentUse shares one Client between goroutines which solve
+// and report different captcha types. Run it with -race.
+func TestClientConcurrentUse(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	srv.SetProcessingRounds(2)
+	ac := srv.Client()
+	ctx := context.Background()
+
+	solvers := []struct {
+		name   string
+		report string
+		solve  func() (int, error)
+	}{
+		{
+			name:   "recaptcha",
+			report: "reportIncorrectRecaptcha",
+			solve: func() (int, error) {
+				solution, err := ac.SolveRecaptchaV2Result(ctx, anticaptcha.RecaptchaV2{WebsiteURL: "https://example.com/", WebsiteKey: "key"})
+				if err != nil {
+					return 0, err
+				}
+				return solution.TaskID, ac.ReportIncorrectRecaptchaByID(ctx, solution.TaskID)
+			},
+		},
+		{
+			name:   "hcaptcha",
+			report: "reportIncorrectHcaptcha",
+			solve: func() (int, error) {
+				solution, err := ac.SolveHcaptchaResult(ctx, anticaptcha.Hcaptcha{WebsiteURL: "https://example.com/", WebsiteKey: "key"})
+				if err != nil {
+					return 0, err
+				}
+				return solution.TaskID, ac.ReportIncorrectHcaptchaByID(ctx, solution.TaskID)
+			},
+		},
+		{
+			name:   "image",
+			report: "reportIncorrectImageCaptcha",
+			solve: func() (int, error) {
+				solution, err := ac.SolveImageResult(ctx, "aW1hZ2U=", anticaptcha.ImageSettings{})
+				if err != nil {
+					return 0, err
+				}
+				return solution.TaskID, ac.ReportIncorrectImageCaptchaByID(ctx, solution.TaskID)
+			},
+		},
+		{
+			name: "legacy recaptcha",
+			solve: func() (int, error) {
+				if _, err := ac.SolveRecaptchaV2(anticaptcha.RecaptchaV2{WebsiteURL: "https://example.com/", WebsiteKey: "key"}); err != nil {
+					return 0, err
+				}
+				_ = ac.GetCookies()
+				return 0, nil
+			},
+		},
+	}
+
+	const rounds = 10
+	var mu sync.Mutex
+	taskIDs := make(map[int]string)
+	var wg sync.WaitGroup
+	for i := 0; i < rounds; i++ {
+		for _, s := range solvers {
+			wg.Add(1)
+			go func(name string, solve func() (int, error)) {
+				defer wg.Done()
+				taskID, err := solve()
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					return
+				}
+				if taskID == 0 {
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if other, ok := taskIDs[taskID]; ok {
+					t.Errorf("%s: task ID %d already returned to %s", name, taskID, other)
+				}
+				taskIDs[taskID] = name
+			}(s.name, s.solve)
+		}
+	}
+	wg.Wait()
+
+	if len(taskIDs) != rounds*3 {
+		t.Fatalf("got %d distinct task IDs, want %d", len(taskIDs), rounds*3)
+	}
+	for _, s := range solvers {
+		if s.report == "" {
+			continue
+		}
+		for _, request := range srv.Requests(s.report) {
+			taskID := int(request.Payload["taskId"].(float64))
+			if taskIDs[taskID] != s.name {
+				t.Errorf("%s reported task %d of %s", s.report, taskID, taskIDs[taskID])
+			}
+		}
+		if got := len(srv.Requests(s.report)); got != rounds {
+			t.Errorf("%s: got %d reports, want %d", s.report, got, rounds)
+		}
+	}
+}
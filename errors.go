@@ -0,0 +1,121 @@
+package anticaptcha
+
+import (
+	"errors"
+	"fmt"
+)
+
+// APIError is returned when the API responds with a non-zero errorId.
+// Its Error method returns the bare error code, so existing comparisons
+// against codes like "ERROR_ZERO_BALANCE" keep working.
+type APIError struct {
+	ID          int
+	Code        string
+	Description string
+	Method      string
+	TaskID      int
+}
+
+func (e *APIError) Error() string {
+	return e.Code
+}
+
+// Is reports whether target is an *APIError with the same error code,
+// which makes errors.Is work against the Err* sentinels.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// IsRetryable reports whether the same request may succeed if repeated later.
+func (e *APIError) IsRetryable() bool {
+	return retryableErrorCodes[e.Code]
+}
+
+// IsFatal reports whether the error is account-level and no request will
+// succeed until the account or key is fixed.
+func (e *APIError) IsFatal() bool {
+	return fatalErrorCodes[e.Code]
+}
+
+func newAPIError(response map[string]interface{}, method string, taskID int) *APIError {
+	apiErr := &APIError{
+		ID:     int(floatValue(response["errorId"])),
+		Method: method,
+		TaskID: taskID,
+	}
+	apiErr.Code, _ = response["errorCode"].(string)
+	apiErr.Description, _ = response["errorDescription"].(string)
+	return apiErr
+}
+
+// EndpointError is returned when an endpoint answers with a 5xx status or a
+// non-JSON error page. Like network errors, it moves requests on to the next
+// fallback endpoint and is retried by a RetryPolicy.
+type EndpointError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *EndpointError) Error() string {
+	return fmt.Sprintf("endpoint %s responded with HTTP %d", e.URL, e.StatusCode)
+}
+
+var ErrInvalidResponse = errors.New("Incorrect API response, something is wrong")
+
+var (
+	ErrKeyDoesNotExist              = &APIError{Code: "ERROR_KEY_DOES_NOT_EXIST"}
+	ErrNoSlotAvailable              = &APIError{Code: "ERROR_NO_SLOT_AVAILABLE"}
+	ErrZeroCaptchaFilesize          = &APIError{Code: "ERROR_ZERO_CAPTCHA_FILESIZE"}
+	ErrTooBigCaptchaFilesize        = &APIError{Code: "ERROR_TOO_BIG_CAPTCHA_FILESIZE"}
+	ErrZeroBalance                  = &APIError{Code: "ERROR_ZERO_BALANCE"}
+	ErrIPNotAllowed                 = &APIError{Code: "ERROR_IP_NOT_ALLOWED"}
+	ErrCaptchaUnsolvable            = &APIError{Code: "ERROR_CAPTCHA_UNSOLVABLE"}
+	ErrBadDuplicates                = &APIError{Code: "ERROR_BAD_DUPLICATES"}
+	ErrNoSuchMethod                 = &APIError{Code: "ERROR_NO_SUCH_METHOD"}
+	ErrImageTypeNotSupported        = &APIError{Code: "ERROR_IMAGE_TYPE_NOT_SUPPORTED"}
+	ErrNoSuchCaptchaID              = &APIError{Code: "ERROR_NO_SUCH_CAPCHA_ID"}
+	ErrIPBlocked                    = &APIError{Code: "ERROR_IP_BLOCKED"}
+	ErrTaskAbsent                   = &APIError{Code: "ERROR_TASK_ABSENT"}
+	ErrTaskNotSupported             = &APIError{Code: "ERROR_TASK_NOT_SUPPORTED"}
+	ErrIncorrectSessionData         = &APIError{Code: "ERROR_INCORRECT_SESSION_DATA"}
+	ErrProxyConnectRefused          = &APIError{Code: "ERROR_PROXY_CONNECT_REFUSED"}
+	ErrProxyConnectTimeout          = &APIError{Code: "ERROR_PROXY_CONNECT_TIMEOUT"}
+	ErrProxyReadTimeout             = &APIError{Code: "ERROR_PROXY_READ_TIMEOUT"}
+	ErrProxyBanned                  = &APIError{Code: "ERROR_PROXY_BANNED"}
+	ErrProxyTransparent             = &APIError{Code: "ERROR_PROXY_TRANSPARENT"}
+	ErrProxyHasNoImageSupport       = &APIError{Code: "ERROR_PROXY_HAS_NO_IMAGE_SUPPORT"}
+	ErrProxyIncompatibleHTTPVersion = &APIError{Code: "ERROR_PROXY_INCOMPATIBLE_HTTP_VERSION"}
+	ErrProxyNotAuthorised           = &APIError{Code: "ERROR_PROXY_NOT_AUTHORISED"}
+	ErrRecaptchaTimeout             = &APIError{Code: "ERROR_RECAPTCHA_TIMEOUT"}
+	ErrRecaptchaInvalidSitekey      = &APIError{Code: "ERROR_RECAPTCHA_INVALID_SITEKEY"}
+	ErrRecaptchaInvalidDomain       = &APIError{Code: "ERROR_RECAPTCHA_INVALID_DOMAIN"}
+	ErrRecaptchaOldBrowser          = &APIError{Code: "ERROR_RECAPTCHA_OLD_BROWSER"}
+	ErrTokenExpired                 = &APIError{Code: "ERROR_TOKEN_EXPIRED"}
+	ErrInvisibleRecaptcha           = &APIError{Code: "ERROR_INVISIBLE_RECAPTCHA"}
+	ErrVisibleRecaptcha             = &APIError{Code: "ERROR_VISIBLE_RECAPTCHA"}
+	ErrFailedLoadingWidget          = &APIError{Code: "ERROR_FAILED_LOADING_WIDGET"}
+	ErrAllWorkersFiltered           = &APIError{Code: "ERROR_ALL_WORKERS_FILTERED"}
+	ErrAccountSuspended             = &APIError{Code: "ERROR_ACCOUNT_SUSPENDED"}
+	ErrTemplateNotFound             = &APIError{Code: "ERROR_TEMPLATE_NOT_FOUND"}
+	ErrTaskCanceled                 = &APIError{Code: "ERROR_TASK_CANCELED"}
+	ErrEmptyComment                 = &APIError{Code: "ERROR_EMPTY_COMMENT"}
+)
+
+var retryableErrorCodes = map[string]bool{
+	ErrNoSlotAvailable.Code:     true,
+	ErrCaptchaUnsolvable.Code:   true,
+	ErrProxyConnectTimeout.Code: true,
+	ErrProxyReadTimeout.Code:    true,
+	ErrRecaptchaTimeout.Code:    true,
+	ErrFailedLoadingWidget.Code: true,
+	ErrTaskCanceled.Code:        true,
+}
+
+var fatalErrorCodes = map[string]bool{
+	ErrKeyDoesNotExist.Code:  true,
+	ErrZeroBalance.Code:      true,
+	ErrIPNotAllowed.Code:     true,
+	ErrIPBlocked.Code:        true,
+	ErrAccountSuspended.Code: true,
+}
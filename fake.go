@@ -0,0 +1,119 @@
+package anticaptcha
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeSolver is an in-memory Solver, BalanceChecker and Reporter for unit
+// tests. It solves every task instantly with the solution set for its type.
+type FakeSolver struct {
+	mu        sync.Mutex
+	solutions map[string]map[string]interface{}
+	err       error
+	balance   float64
+	cost      float64
+	lastID    int
+	tasks     []map[string]interface{}
+	reports   []FakeReport
+}
+
+type FakeReport struct {
+	TaskType string
+	TaskID   int
+	Correct  bool
+}
+
+var _ interface {
+	Solver
+	BalanceChecker
+	Reporter
+} = (*FakeSolver)(nil)
+
+func NewFakeSolver() *FakeSolver {
+	return &FakeSolver{
+		solutions: make(map[string]map[string]interface{}),
+	}
+}
+
+// SetSolution sets the solution returned for tasks of taskType.
+func (f *FakeSolver) SetSolution(taskType string, solution map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.solutions[taskType] = solution
+}
+
+// SetError makes every following call fail with err, until it is set to nil.
+func (f *FakeSolver) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func (f *FakeSolver) SetBalance(balance float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balance = balance
+}
+
+// SetCost sets the cost of each solved task. It is deducted from the balance.
+func (f *FakeSolver) SetCost(cost float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cost = cost
+}
+
+func (f *FakeSolver) Solve(ctx context.Context, task map[string]interface{}) (*TaskResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks = append(f.tasks, task)
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.lastID++
+	f.balance -= f.cost
+	taskType, _ := task["type"].(string)
+	return &TaskResult{
+		TaskMeta: TaskMeta{
+			TaskID: f.lastID,
+			Cost:   f.cost,
+		},
+		Solution: f.solutions[taskType],
+	}, nil
+}
+
+func (f *FakeSolver) GetBalanceContext(ctx context.Context) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.balance, nil
+}
+
+func (f *FakeSolver) Report(ctx context.Context, taskType string, taskID int, correct bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.reports = append(f.reports, FakeReport{TaskType: taskType, TaskID: taskID, Correct: correct})
+	return nil
+}
+
+// Tasks returns the tasks passed to Solve so far.
+func (f *FakeSolver) Tasks() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.tasks...)
+}
+
+// Reports returns the reports received so far.
+func (f *FakeSolver) Reports() []FakeReport {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeReport(nil), f.reports...)
+}
@@ -0,0 +1,232 @@
+package anticaptcha
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type KeyPolicy int
+
+const (
+	KeyPolicyRoundRobin KeyPolicy = iota
+	KeyPolicyHighestBalance
+	KeyPolicyWeighted
+)
+
+var ErrNoKeysAvailable = errors.New("no API keys available in the key pool")
+
+var ErrInvalidInterval = errors.New("interval must be positive")
+
+// ErrUnknownKey is returned for a TaskMeta whose KeyID matches neither the
+// provider's own key nor a key in the KeyPool.
+var ErrUnknownKey = errors.New("unknown API key ID")
+
+// KeyID returns a short identifier of an API key, which is safe to log and
+// store in place of the key itself.
+func KeyID(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// KeyPool holds several API keys and picks one for each createTask call.
+// Keys which return ERROR_ZERO_BALANCE or ERROR_KEY_DOES_NOT_EXIST are taken
+// out of rotation until a balance refresh finds them usable again.
+type KeyPool struct {
+	mu     sync.Mutex
+	policy KeyPolicy
+	keys   []*pooledKey
+	next   int
+}
+
+type pooledKey struct {
+	key      string
+	weight   int
+	balance  float64
+	err      error
+	disabled bool
+}
+
+// KeyStatus is a snapshot of one key in a KeyPool. Err is the error which
+// took the key out of rotation, if any.
+type KeyStatus struct {
+	Key     string
+	ID      string
+	Weight  int
+	Balance float64
+	Active  bool
+	Err     error
+}
+
+func NewKeyPool(policy KeyPolicy, keys ...string) *KeyPool {
+	p := &KeyPool{policy: policy}
+	for _, key := range keys {
+		p.AddKey(key, 1)
+	}
+	return p
+}
+
+// AddKey adds a key with the weight used by KeyPolicyWeighted.
+func (p *KeyPool) AddKey(key string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, &pooledKey{key: key, weight: weight})
+}
+
+func (p *KeyPool) Status() []KeyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]KeyStatus, 0, len(p.keys))
+	for _, k := range p.keys {
+		status = append(status, KeyStatus{
+			Key:     k.key,
+			ID:      KeyID(k.key),
+			Weight:  k.weight,
+			Balance: k.balance,
+			Active:  !k.disabled,
+			Err:     k.err,
+		})
+	}
+	return status
+}
+
+// Pick returns the next key according to the pool's policy.
+func (p *KeyPool) Pick() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var active []*pooledKey
+	for _, k := range p.keys {
+		if !k.disabled {
+			active = append(active, k)
+		}
+	}
+	if len(active) == 0 {
+		return "", ErrNoKeysAvailable
+	}
+
+	switch p.policy {
+	case KeyPolicyHighestBalance:
+		best := active[0]
+		for _, k := range active[1:] {
+			if k.balance > best.balance {
+				best = k
+			}
+		}
+		return best.key, nil
+	case KeyPolicyWeighted:
+		total := 0
+		for _, k := range active {
+			total += k.weight
+		}
+		n := rand.Intn(total)
+		for _, k := range active {
+			if n < k.weight {
+				return k.key, nil
+			}
+			n -= k.weight
+		}
+	}
+	k := active[p.next%len(active)]
+	p.next++
+	return k.key, nil
+}
+
+// lookup returns the key with the given KeyID.
+func (p *KeyPool) lookup(id string) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if KeyID(k.key) == id {
+			return k.key, true
+		}
+	}
+	return "", false
+}
+
+// Disable takes the key out of rotation because of err.
+func (p *KeyPool) Disable(key string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if k.key == key {
+			k.disabled = true
+			k.err = err
+		}
+	}
+}
+
+// Refresh checks the balance of every key with getBalance through the
+// client's endpoint. Keys with a positive balance are put back into rotation,
+// the others are taken out.
+func (p *KeyPool) Refresh(ctx context.Context, ac *Client) error {
+	p.mu.Lock()
+	keys := make([]string, 0, len(p.keys))
+	for _, k := range p.keys {
+		keys = append(keys, k.key)
+	}
+	p.mu.Unlock()
+
+	provider := ac.defaultProvider()
+	for _, key := range keys {
+		response, err := ac.request(ctx, provider, "getBalance", map[string]interface{}{"clientKey": key})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		balance := floatValue(response["balance"])
+		if err == nil && balance <= 0 {
+			err = ErrZeroBalance
+		}
+
+		p.mu.Lock()
+		for _, k := range p.keys {
+			if k.key != key {
+				continue
+			}
+			if err == nil {
+				k.balance = balance
+				k.disabled = false
+				k.err = nil
+			} else if disablesKey(err) {
+				k.balance = 0
+				k.disabled = true
+				k.err = err
+			}
+		}
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// Run refreshes the pool every interval until ctx is done. A non-positive
+// interval returns ErrInvalidInterval.
+func (p *KeyPool) Run(ctx context.Context, ac *Client, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Refresh(ctx, ac); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func disablesKey(err error) bool {
+	return errors.Is(err, ErrZeroBalance) || errors.Is(err, ErrKeyDoesNotExist)
+}
@@ -0,0 +1,188 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+func TestKeyPoolPick(t *testing.T) {
+	t.Run("round robin", func(t *testing.T) {
+		pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyRoundRobin, "a", "b", "c")
+		pool.Disable("b", anticaptcha.ErrZeroBalance)
+		var got []string
+		for i := 0; i < 4; i++ {
+			key, err := pool.Pick()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, key)
+		}
+		if got[0] != "a" || got[1] != "c" || got[2] != "a" || got[3] != "c" {
+			t.Errorf("picked %v, want a and c in turn", got)
+		}
+	})
+
+	t.Run("highest balance", func(t *testing.T) {
+		srv := anticaptchatest.NewServer()
+		defer srv.Close()
+		srv.SetKeyBalance("a", 1)
+		srv.SetKeyBalance("b", 5)
+		srv.SetKeyBalance("c", 3)
+		pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyHighestBalance, "a", "b", "c")
+		if err := pool.Refresh(context.Background(), srv.Client()); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if key, err := pool.Pick(); err != nil || key != "b" {
+				t.Fatalf("picked %q, %v, want b", key, err)
+			}
+		}
+		pool.Disable("b", anticaptcha.ErrZeroBalance)
+		if key, err := pool.Pick(); err != nil || key != "c" {
+			t.Errorf("picked %q, %v, want c once b is disabled", key, err)
+		}
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+		pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyWeighted)
+		pool.AddKey("heavy", 3)
+		pool.AddKey("light", 1)
+		counts := make(map[string]int)
+		for i := 0; i < 4000; i++ {
+			key, err := pool.Pick()
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts[key]++
+		}
+		if counts["heavy"] < 2700 || counts["heavy"] > 3300 {
+			t.Errorf("picked %v, want heavy about 3 times as often as light", counts)
+		}
+	})
+
+	t.Run("no active keys", func(t *testing.T) {
+		pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyRoundRobin, "a")
+		pool.Disable("a", anticaptcha.ErrKeyDoesNotExist)
+		if _, err := pool.Pick(); !errors.Is(err, anticaptcha.ErrNoKeysAvailable) {
+			t.Errorf("got %v, want ErrNoKeysAvailable", err)
+		}
+	})
+}
+
+func TestKeyPoolDisablesKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(*anticaptchatest.Server)
+		want   error
+		active string
+	}{
+		{
+			name: "unknown key",
+			setup: func(srv *anticaptchatest.Server) {
+				srv.AddKey("good")
+			},
+			want:   anticaptcha.ErrKeyDoesNotExist,
+			active: "good",
+		},
+		{
+			name: "zero balance",
+			setup: func(srv *anticaptchatest.Server) {
+				srv.AddKey("bad")
+				srv.AddKey("good")
+				srv.InjectError("createTask", "ERROR_ZERO_BALANCE")
+			},
+			want:   anticaptcha.ErrZeroBalance,
+			active: "good",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			tt.setup(srv)
+			pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyRoundRobin, "bad", "good")
+			ac := srv.Client(anticaptcha.WithKeyPool(pool))
+
+			result, err := ac.Solve(context.Background(), imageTask)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.KeyID != anticaptcha.KeyID("good") {
+				t.Errorf("solved with key ID %q, want the ID of good", result.KeyID)
+			}
+			for _, status := range pool.Status() {
+				if status.Active != (status.Key == tt.active) {
+					t.Errorf("key %s active = %v", status.Key, status.Active)
+				}
+				if status.Key == "bad" && !errors.Is(status.Err, tt.want) {
+					t.Errorf("bad key disabled by %v, want %v", status.Err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyPoolRefresh(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	srv.AddKey("topped-up")
+	pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyRoundRobin, "topped-up", "unknown")
+	pool.Disable("topped-up", anticaptcha.ErrZeroBalance)
+
+	if err := pool.Refresh(context.Background(), srv.Client()); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range pool.Status() {
+		switch status.Key {
+		case "topped-up":
+			if !status.Active || status.Err != nil || status.Balance != 10 {
+				t.Errorf("topped-up key: got %+v, want it active with balance 10", status)
+			}
+		case "unknown":
+			if status.Active || !errors.Is(status.Err, anticaptcha.ErrKeyDoesNotExist) {
+				t.Errorf("unknown key: got %+v, want it disabled", status)
+			}
+		}
+	}
+}
+
+func TestKeyPoolFollowUpCalls(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	srv.AddKey("pooled")
+	pool := anticaptcha.NewKeyPool(anticaptcha.KeyPolicyRoundRobin, "pooled")
+	ac := srv.Client(anticaptcha.WithKeyPool(pool))
+	ctx := context.Background()
+
+	solution, err := ac.SolveRecaptchaV2Result(ctx, anticaptcha.RecaptchaV2{WebsiteURL: "https://example.com/", WebsiteKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ac.ReportResult(ctx, solution.TaskMeta, false); err != nil {
+		t.Fatal(err)
+	}
+	task, err := ac.TaskByMeta(solution.TaskMeta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, method := range []string{"getTaskResult", "reportIncorrectRecaptcha"} {
+		for _, request := range srv.Requests(method) {
+			if request.Payload["clientKey"] != "pooled" {
+				t.Errorf("%s was sent with key %v, want the pooled key", method, request.Payload["clientKey"])
+			}
+		}
+	}
+
+	unknown := solution.TaskMeta
+	unknown.KeyID = anticaptcha.KeyID("not-in-the-pool")
+	if err := ac.ReportResult(ctx, unknown, false); !errors.Is(err, anticaptcha.ErrUnknownKey) {
+		t.Errorf("got %v, want ErrUnknownKey", err)
+	}
+}
@@ -0,0 +1,82 @@
+package anticaptcha
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Logger receives structured events from the client. Fields are passed as
+// alternating keys and values, like in log/slog. The client key is never
+// included in any event.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...interface{})
+}
+
+// NewTextLogger returns a Logger which writes one "level msg key=value" line
+// per event to w. It is used for verbose output when no Logger is set.
+func NewTextLogger(w io.Writer) Logger {
+	return &textLogger{w: w}
+}
+
+type textLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *textLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...interface{}) {
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", fields[i], fields[i+1])
+	}
+	b.WriteString("\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...interface{}) {}
+
+var stdoutLogger = NewTextLogger(os.Stdout)
+
+func (ac *Client) logger() Logger {
+	if !ac.IsVerbose {
+		return nopLogger{}
+	}
+	if ac.Logger != nil {
+		return ac.Logger
+	}
+	return stdoutLogger
+}
@@ -0,0 +1,168 @@
+package anticaptcha
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type BalanceEventKind int
+
+const (
+	// BalanceBelowThreshold fires when the balance drops below a threshold.
+	BalanceBelowThreshold BalanceEventKind = iota
+	// BalanceAboveThreshold fires when the balance rises back to a threshold.
+	BalanceAboveThreshold
+	// BalanceDropping fires when the balance drops faster than the max rate.
+	BalanceDropping
+)
+
+func (k BalanceEventKind) String() string {
+	switch k {
+	case BalanceBelowThreshold:
+		return "below threshold"
+	case BalanceAboveThreshold:
+		return "above threshold"
+	case BalanceDropping:
+		return "dropping"
+	}
+	return "unknown"
+}
+
+// BalanceEvent describes a balance change noticed by a BalanceMonitor. Rate is
+// the balance drop per hour since the previous check.
+type BalanceEvent struct {
+	Kind      BalanceEventKind
+	Balance   float64
+	Previous  float64
+	Threshold float64
+	Rate      float64
+	Time      time.Time
+}
+
+// BalanceMonitor polls the balance in the background and reports threshold
+// crossings and fast drops to callbacks and the Events channel. Configure it
+// before calling Run.
+type BalanceMonitor struct {
+	checker     BalanceChecker
+	interval    time.Duration
+	thresholds  []float64
+	maxDropRate float64
+	callbacks   []func(BalanceEvent)
+	events      chan BalanceEvent
+
+	mu          sync.Mutex
+	balance     float64
+	checkedAt   time.Time
+	initialized bool
+}
+
+func NewBalanceMonitor(checker BalanceChecker, interval time.Duration) *BalanceMonitor {
+	return &BalanceMonitor{
+		checker:  checker,
+		interval: interval,
+		events:   make(chan BalanceEvent, 16),
+	}
+}
+
+// AddThreshold reports when the balance crosses threshold in either direction.
+func (m *BalanceMonitor) AddThreshold(threshold float64) {
+	m.thresholds = append(m.thresholds, threshold)
+}
+
+// SetMaxDropRate reports when the balance drops by more than perHour per hour
+// between two checks.
+func (m *BalanceMonitor) SetMaxDropRate(perHour float64) {
+	m.maxDropRate = perHour
+}
+
+// OnEvent registers a callback. Callbacks run on the monitor's goroutine.
+func (m *BalanceMonitor) OnEvent(callback func(BalanceEvent)) {
+	m.callbacks = append(m.callbacks, callback)
+}
+
+// Events returns a buffered channel of events. Events are dropped when the
+// channel is full.
+func (m *BalanceMonitor) Events() <-chan BalanceEvent {
+	return m.events
+}
+
+// LastBalance returns the balance from the last successful check without
+// calling the API. ok is false before the first check.
+func (m *BalanceMonitor) LastBalance() (balance float64, checkedAt time.Time, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.balance, m.checkedAt, m.initialized
+}
+
+// Run checks the balance every interval until ctx is done. Failed checks are
+// skipped. A non-positive interval returns ErrInvalidInterval.
+func (m *BalanceMonitor) Run(ctx context.Context) error {
+	if m.interval <= 0 {
+		return ErrInvalidInterval
+	}
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check fetches the balance once and fires events for any change.
+func (m *BalanceMonitor) Check(ctx context.Context) error {
+	balance, err := m.checker.GetBalanceContext(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	m.mu.Lock()
+	previous, previousAt, initialized := m.balance, m.checkedAt, m.initialized
+	m.balance, m.checkedAt, m.initialized = balance, now, true
+	m.mu.Unlock()
+
+	var events []BalanceEvent
+	for _, threshold := range m.thresholds {
+		event := BalanceEvent{
+			Balance:   balance,
+			Previous:  previous,
+			Threshold: threshold,
+			Time:      now,
+		}
+		if balance < threshold && (!initialized || previous >= threshold) {
+			event.Kind = BalanceBelowThreshold
+			events = append(events, event)
+		} else if initialized && balance >= threshold && previous < threshold {
+			event.Kind = BalanceAboveThreshold
+			events = append(events, event)
+		}
+	}
+	if initialized && m.maxDropRate > 0 && now.After(previousAt) {
+		rate := (previous - balance) / now.Sub(previousAt).Hours()
+		if rate > m.maxDropRate {
+			events = append(events, BalanceEvent{
+				Kind:     BalanceDropping,
+				Balance:  balance,
+				Previous: previous,
+				Rate:     rate,
+				Time:     now,
+			})
+		}
+	}
+
+	for _, event := range events {
+		for _, callback := range m.callbacks {
+			callback(event)
+		}
+		select {
+		case m.events <- event:
+		default:
+		}
+	}
+	return nil
+}
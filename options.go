@@ -0,0 +1,110 @@
+package anticaptcha
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+// WithBaseURL sets the API endpoint used for all requests.
+func WithBaseURL(baseURL string) Option {
+	return func(ac *Client) {
+		ac.BaseURL = baseURL
+	}
+}
+
+// WithFallbackURLs sets the endpoints tried in order when the base URL is unreachable.
+func WithFallbackURLs(urls ...string) Option {
+	return func(ac *Client) {
+		ac.FallbackURLs = append([]string(nil), urls...)
+	}
+}
+
+// WithHTTPClient makes the client send all API requests through httpClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(ac *Client) {
+		ac.HTTPClient = httpClient
+	}
+}
+
+// WithTransport makes the client send all API requests through transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(ac *Client) {
+		ac.HTTPClient = &http.Client{Transport: transport}
+	}
+}
+
+// WithRetryPolicy enables automatic retries, see RetryPolicy.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(ac *Client) {
+		ac.RetryPolicy = policy
+	}
+}
+
+// WithLogger sends the client's events to logger and enables verbose output.
+func WithLogger(logger Logger) Option {
+	return func(ac *Client) {
+		ac.Logger = logger
+		ac.IsVerbose = true
+	}
+}
+
+// WithRateLimit limits how often the API method is called, see SetRateLimit.
+func WithRateLimit(method string, limit RateLimit) Option {
+	return func(ac *Client) {
+		ac.SetRateLimit(method, limit)
+	}
+}
+
+// WithProviders makes the client create tasks with the given providers, in
+// failover order. Each provider uses its own ClientKey, so a KeyPool is not
+// used together with Providers.
+func WithProviders(providers ...Provider) Option {
+	return func(ac *Client) {
+		ac.Providers = append([]Provider(nil), providers...)
+	}
+}
+
+// WithFailover sets the policy which moves tasks on to the next of the
+// Providers, see FailoverPolicy. The default is FailoverUnavailable.
+func WithFailover(policy FailoverPolicy) Option {
+	return func(ac *Client) {
+		ac.Failover = policy
+	}
+}
+
+// WithKeyPool makes the client pick its key for each task from pool. The pool
+// is ignored when Providers are set.
+func WithKeyPool(pool *KeyPool) Option {
+	return func(ac *Client) {
+		ac.KeyPool = pool
+	}
+}
+
+// WithBudget refuses new tasks once a spending limit of budget is reached.
+func WithBudget(budget *Budget) Option {
+	return func(ac *Client) {
+		ac.Budget = budget
+	}
+}
+
+// WithCallbacks asks the API to post results to url, which must be served by
+// handler. Tasks created by the client take their result from handler and
+// fall back to polling when no callback arrives within timeout. Handles
+// rebuilt from a task ID, for example with TaskByID, always poll.
+func WithCallbacks(url string, handler *CallbackHandler, timeout time.Duration) Option {
+	return func(ac *Client) {
+		ac.CallbackURL = url
+		ac.Callbacks = handler
+		ac.CallbackTimeout = timeout
+	}
+}
+
+// WithProxyPool makes *ProxyOn calls without a proxy pick one from pool.
+func WithProxyPool(pool *ProxyPool) Option {
+	return func(ac *Client) {
+		ac.ProxyPool = pool
+	}
+}
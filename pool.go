@@ -0,0 +1,105 @@
+package anticaptcha
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool solves many tasks concurrently with a fixed number of workers.
+type Pool struct {
+	solver  Solver
+	workers int
+}
+
+// BatchResult is the outcome of one task solved by a Pool. Index is the
+// position of the task in the input slice or channel.
+type BatchResult struct {
+	Index  int
+	Task   map[string]interface{}
+	Result *TaskResult
+	Err    error
+}
+
+func NewPool(solver Solver, workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Pool{
+		solver:  solver,
+		workers: workers,
+	}
+}
+
+// Solve solves tasks with at most p.workers of them in flight and streams the
+// results in completion order. The channel is closed once all tasks are done
+// and must be drained by the caller. There is one result for every task: when
+// ctx is cancelled, tasks in flight and tasks not yet started fail with
+// ctx.Err().
+func (p *Pool) Solve(ctx context.Context, tasks []map[string]interface{}) <-chan BatchResult {
+	index := 0
+	return p.run(ctx, func() (map[string]interface{}, bool) {
+		if index == len(tasks) {
+			return nil, false
+		}
+		index++
+		return tasks[index-1], true
+	})
+}
+
+// SolveChan is like Solve but reads tasks from a channel until it is closed.
+// When ctx is cancelled it stops reading, so tasks still in the channel get
+// no result.
+func (p *Pool) SolveChan(ctx context.Context, tasks <-chan map[string]interface{}) <-chan BatchResult {
+	return p.run(ctx, func() (map[string]interface{}, bool) {
+		select {
+		case task, ok := <-tasks:
+			return task, ok
+		case <-ctx.Done():
+			return nil, false
+		}
+	})
+}
+
+// run hands the tasks returned by next to the workers. Tasks which can't be
+// started because ctx is done fail with ctx.Err().
+func (p *Pool) run(ctx context.Context, next func() (map[string]interface{}, bool)) <-chan BatchResult {
+	jobs := make(chan BatchResult)
+	results := make(chan BatchResult)
+
+	var wg sync.WaitGroup
+	wg.Add(p.workers + 1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for index := 0; ; index++ {
+			task, ok := next()
+			if !ok {
+				return
+			}
+			job := BatchResult{Index: index, Task: task}
+			if ctx.Err() == nil {
+				select {
+				case jobs <- job:
+					continue
+				case <-ctx.Done():
+				}
+			}
+			job.Err = ctx.Err()
+			results <- job
+		}
+	}()
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.Result, job.Err = p.solver.Solve(ctx, job.Task)
+				results <- job
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
@@ -0,0 +1,81 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+func TestPoolSolve(t *testing.T) {
+	tests := []struct {
+		name        string
+		latency     time.Duration
+		inject      string
+		cancelAfter time.Duration
+		wantSolved  func(solved int) bool
+		wantErr     error
+	}{
+		{
+			name:       "all solved",
+			wantSolved: func(solved int) bool { return solved == 10 },
+		},
+		{
+			name:       "per task error",
+			inject:     "ERROR_NO_SLOT_AVAILABLE",
+			wantSolved: func(solved int) bool { return solved == 9 },
+			wantErr:    anticaptcha.ErrNoSlotAvailable,
+		},
+		{
+			name:        "cancelled mid-batch",
+			latency:     20 * time.Millisecond,
+			cancelAfter: 100 * time.Millisecond,
+			wantSolved:  func(solved int) bool { return solved > 0 && solved < 10 },
+			wantErr:     context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			srv.SetLatency(tt.latency)
+			if tt.inject != "" {
+				srv.InjectError("createTask", tt.inject)
+			}
+			ac := srv.Client()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelAfter > 0 {
+				time.AfterFunc(tt.cancelAfter, cancel)
+			}
+			tasks := make([]map[string]interface{}, 10)
+			for i := range tasks {
+				tasks[i] = map[string]interface{}{"type": "ImageToTextTask", "body": "aW1hZ2U="}
+			}
+
+			seen := make(map[int]bool)
+			solved := 0
+			for result := range anticaptcha.NewPool(ac, 2).Solve(ctx, tasks) {
+				if seen[result.Index] {
+					t.Errorf("task %d reported twice", result.Index)
+				}
+				seen[result.Index] = true
+				if result.Err == nil {
+					solved++
+				} else if !errors.Is(result.Err, tt.wantErr) {
+					t.Errorf("task %d: got error %v, want %v", result.Index, result.Err, tt.wantErr)
+				}
+			}
+			if len(seen) != len(tasks) {
+				t.Errorf("got results for %d tasks, want %d", len(seen), len(tasks))
+			}
+			if !tt.wantSolved(solved) {
+				t.Errorf("unexpected number of solved tasks: %d", solved)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package anticaptcha
+
+import (
+	"errors"
+	"net"
+	"net/http"
+)
+
+const DefaultProviderName = "anti-captcha"
+
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// Provider is a service which speaks the createTask/getTaskResult protocol,
+// with its own endpoints and client key.
+type Provider struct {
+	Name         string
+	BaseURL      string
+	FallbackURLs []string
+	ClientKey    string
+}
+
+func (p Provider) endpoints() []string {
+	baseURL := p.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return append([]string{baseURL}, p.FallbackURLs...)
+}
+
+// defaultProvider is the provider described by the Client's own BaseURL,
+// FallbackURLs and ClientKey.
+func (ac *Client) defaultProvider() Provider {
+	return Provider{
+		Name:         DefaultProviderName,
+		BaseURL:      ac.BaseURL,
+		FallbackURLs: ac.FallbackURLs,
+		ClientKey:    ac.ClientKey,
+	}
+}
+
+// providers returns the providers tasks are created with, in failover order.
+// When Providers is set, the Client's own endpoint and key are not used for
+// tasks unless they are listed there too.
+func (ac *Client) providers() []Provider {
+	if len(ac.Providers) > 0 {
+		return ac.Providers
+	}
+	return []Provider{ac.defaultProvider()}
+}
+
+func (ac *Client) provider(name string) (Provider, bool) {
+	for _, provider := range ac.providers() {
+		if provider.Name == name {
+			return provider, true
+		}
+	}
+	return Provider{}, false
+}
+
+// metaProvider returns the provider and key which created the task described
+// by meta. Tasks without a provider name belong to the Client's own endpoint.
+// Keys other than the provider's own are looked up in the KeyPool.
+func (ac *Client) metaProvider(meta TaskMeta) (Provider, error) {
+	provider, ok := ac.provider(meta.Provider)
+	if !ok {
+		if meta.Provider != "" && meta.Provider != DefaultProviderName {
+			return Provider{}, ErrUnknownProvider
+		}
+		provider = ac.defaultProvider()
+	}
+	if meta.KeyID == "" || meta.KeyID == KeyID(provider.ClientKey) {
+		return provider, nil
+	}
+	if ac.KeyPool == nil {
+		return Provider{}, ErrUnknownKey
+	}
+	key, ok := ac.KeyPool.lookup(meta.KeyID)
+	if !ok {
+		return Provider{}, ErrUnknownKey
+	}
+	provider.ClientKey = key
+	return provider, nil
+}
+
+// FailoverPolicy reports whether a failed createTask call moves the task on
+// to the next provider. Failing over after the request reached a provider may
+// create the task twice: the first provider can still solve and charge for it
+// while the next one works on the copy. Policies trade that risk against how
+// quickly tasks leave a provider which is down.
+type FailoverPolicy func(err error) bool
+
+// FailoverUnavailable is the default FailoverPolicy. It fails over when the
+// provider can't be reached, answers ERROR_NO_SLOT_AVAILABLE, or a gateway in
+// front of it answers 502, 503 or 504. Only a 504 can hide a task which was
+// created after all.
+func FailoverUnavailable(err error) bool {
+	if notSent(err) || errors.Is(err, ErrNoSlotAvailable) {
+		return true
+	}
+	var endpointErr *EndpointError
+	if errors.As(err, &endpointErr) {
+		switch endpointErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// FailoverOnTimeout fails over like FailoverUnavailable and also when a
+// provider doesn't answer in time, which is more likely to create a task
+// twice.
+func FailoverOnTimeout(err error) bool {
+	var netErr net.Error
+	return FailoverUnavailable(err) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (ac *Client) failsOver(err error) bool {
+	if ac.Failover != nil {
+		return ac.Failover(err)
+	}
+	return FailoverUnavailable(err)
+}
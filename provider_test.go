@@ -0,0 +1,102 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+var imageTask = map[string]interface{}{
+	"type": "ImageToTextTask",
+	"body": "aW1hZ2U=",
+}
+
+func TestProviderFailover(t *testing.T) {
+	status := func(code int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "<html>unavailable</html>", code)
+		}
+	}
+	hang := func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice when the client gives up.
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}
+	noSlot := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"errorId":2,"errorCode":"ERROR_NO_SLOT_AVAILABLE"}`))
+	}
+
+	tests := []struct {
+		name     string
+		primary  http.HandlerFunc
+		policy   anticaptcha.FailoverPolicy
+		wantErr  bool
+		provider string
+	}{
+		{name: "no slot", primary: noSlot, provider: "backup"},
+		{name: "502", primary: status(http.StatusBadGateway), provider: "backup"},
+		{name: "503", primary: status(http.StatusServiceUnavailable), provider: "backup"},
+		{name: "504", primary: status(http.StatusGatewayTimeout), provider: "backup"},
+		{name: "500", primary: status(http.StatusInternalServerError), wantErr: true},
+		{name: "timeout", primary: hang, wantErr: true},
+		{name: "timeout with FailoverOnTimeout", primary: hang, policy: anticaptcha.FailoverOnTimeout, provider: "backup"},
+		{
+			name:    "custom policy",
+			primary: status(http.StatusServiceUnavailable),
+			policy:  func(err error) bool { return errors.Is(err, anticaptcha.ErrNoSlotAvailable) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				tt.primary(w, r)
+			}))
+			defer primary.Close()
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+
+			ac := srv.Client(
+				anticaptcha.WithHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}),
+				anticaptcha.WithProviders(
+					anticaptcha.Provider{Name: "primary", BaseURL: primary.URL, ClientKey: anticaptchatest.ClientKey},
+					anticaptcha.Provider{Name: "backup", BaseURL: srv.URL, ClientKey: anticaptchatest.ClientKey},
+				),
+				anticaptcha.WithFailover(tt.policy),
+			)
+			result, err := ac.Solve(context.Background(), imageTask)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("task was solved by %q, want an error", result.Provider)
+				}
+				if n := len(srv.Requests("createTask")); n != 0 {
+					t.Errorf("backup received %d createTask requests, want 0", n)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Provider != tt.provider {
+					t.Errorf("solved by %q, want %q", result.Provider, tt.provider)
+				}
+			}
+			if n := atomic.LoadInt32(&calls); n != 1 {
+				t.Errorf("primary received %d requests, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package anticaptcha
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ProxyPolicy int
+
+const (
+	ProxyPolicyRoundRobin ProxyPolicy = iota
+	ProxyPolicyRandom
+	// ProxyPolicySticky keeps using the same proxy for a WebsiteURL until it
+	// is quarantined.
+	ProxyPolicySticky
+)
+
+var ErrNoProxiesAvailable = errors.New("no proxies available in the proxy pool")
+
+// ProxyPool holds proxies and picks one for each *ProxyOn call made without a
+// proxy. A proxy which fails with ERROR_PROXY_* errors several times in a row
+// is quarantined for a cool-down period.
+type ProxyPool struct {
+	mu          sync.Mutex
+	policy      ProxyPolicy
+	maxFailures int
+	cooldown    time.Duration
+	proxies     []*pooledProxy
+	next        int
+	sticky      map[string]*pooledProxy
+}
+
+type pooledProxy struct {
+	proxy       *Proxy
+	failures    int
+	err         error
+	quarantined time.Time
+}
+
+// ProxyStatus is a snapshot of one proxy in a ProxyPool. Err is the last
+// proxy error, and QuarantinedUntil is zero for proxies in rotation.
+type ProxyStatus struct {
+	Proxy            *Proxy
+	Failures         int
+	Err              error
+	QuarantinedUntil time.Time
+}
+
+func NewProxyPool(policy ProxyPolicy, proxies ...*Proxy) *ProxyPool {
+	p := &ProxyPool{
+		policy:      policy,
+		maxFailures: 3,
+		cooldown:    10 * time.Minute,
+		sticky:      make(map[string]*pooledProxy),
+	}
+	for _, proxy := range proxies {
+		p.Add(proxy)
+	}
+	return p
+}
+
+func (p *ProxyPool) Add(proxy *Proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.proxies = append(p.proxies, &pooledProxy{proxy: proxy})
+}
+
+// Load adds one proxy per line in any format accepted by ParseProxy. Blank
+// lines and lines starting with # are skipped.
+func (p *ProxyPool) Load(r io.Reader) error {
+	var proxies []*Proxy
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		proxy, err := ParseProxy(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		proxies = append(proxies, proxy)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, proxy := range proxies {
+		p.Add(proxy)
+	}
+	return nil
+}
+
+// LoadFile adds the proxies listed in the file, see Load.
+func (p *ProxyPool) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := p.Load(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// SetQuarantine quarantines a proxy for cooldown after failures proxy errors
+// in a row. The defaults are 3 failures and 10 minutes.
+func (p *ProxyPool) SetQuarantine(failures int, cooldown time.Duration) {
+	if failures < 1 {
+		failures = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxFailures = failures
+	p.cooldown = cooldown
+}
+
+func (p *ProxyPool) Status() []ProxyStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	status := make([]ProxyStatus, 0, len(p.proxies))
+	for _, pp := range p.proxies {
+		s := ProxyStatus{
+			Proxy:    pp.proxy,
+			Failures: pp.failures,
+			Err:      pp.err,
+		}
+		if pp.quarantined.After(now) {
+			s.QuarantinedUntil = pp.quarantined
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// Pick returns a proxy for a task on websiteURL according to the pool's
+// policy, skipping quarantined proxies.
+func (p *ProxyPool) Pick(websiteURL string) (*Proxy, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var active []*pooledProxy
+	for _, pp := range p.proxies {
+		if !pp.quarantined.After(now) {
+			active = append(active, pp)
+		}
+	}
+	if len(active) == 0 {
+		return nil, ErrNoProxiesAvailable
+	}
+
+	switch p.policy {
+	case ProxyPolicyRandom:
+		return active[rand.Intn(len(active))].proxy, nil
+	case ProxyPolicySticky:
+		if pp, ok := p.sticky[websiteURL]; ok && !pp.quarantined.After(now) {
+			return pp.proxy, nil
+		}
+		pp := active[p.next%len(active)]
+		p.next++
+		p.sticky[websiteURL] = pp
+		return pp.proxy, nil
+	}
+	pp := active[p.next%len(active)]
+	p.next++
+	return pp.proxy, nil
+}
+
+// Report records the outcome of a task solved with proxy. ERROR_PROXY_*
+// errors count towards quarantine, success resets the count and other errors
+// are ignored. Proxies which are not in the pool are ignored too.
+func (p *ProxyPool) Report(proxy *Proxy, err error) {
+	if err != nil && !isProxyFailure(err) {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pp := range p.proxies {
+		if pp.proxy != proxy && (pp.proxy == nil || proxy == nil || *pp.proxy != *proxy) {
+			continue
+		}
+		if err == nil {
+			pp.failures = 0
+			continue
+		}
+		pp.failures++
+		pp.err = err
+		if pp.failures >= p.maxFailures {
+			pp.failures = 0
+			pp.quarantined = time.Now().Add(p.cooldown)
+		}
+	}
+}
+
+func isProxyFailure(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && strings.HasPrefix(apiErr.Code, "ERROR_PROXY_")
+}
+
+// proxyFor returns the proxy for a *ProxyOn call: the given one, or one from
+// ProxyPool when none is given.
+func (ac *Client) proxyFor(proxy *Proxy, websiteURL string) (*Proxy, error) {
+	if proxy == nil && ac.ProxyPool != nil {
+		var err error
+		if proxy, err = ac.ProxyPool.Pick(websiteURL); err != nil {
+			return nil, err
+		}
+	}
+	return proxy, proxy.Validate()
+}
+
+// optionalProxy is proxyFor for tasks which also work without a proxy, like
+// AntiGate. Without a proxy and a ProxyPool it returns nil.
+func (ac *Client) optionalProxy(proxy *Proxy, websiteURL string) (*Proxy, error) {
+	if proxy == nil && ac.ProxyPool == nil {
+		return nil, nil
+	}
+	return ac.proxyFor(proxy, websiteURL)
+}
+
+func (ac *Client) reportProxy(proxy *Proxy, err error) {
+	if ac.ProxyPool != nil {
+		ac.ProxyPool.Report(proxy, err)
+	}
+}
@@ -0,0 +1,86 @@
+package anticaptcha
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket budget: Rate requests per second on average,
+// with up to Burst requests at once.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// SetRateLimit limits how often the API method is called. Callers over the
+// budget block until a token is available or their context is done. An empty
+// method name sets the budget shared by all methods without their own limit.
+// A zero Rate removes the limit.
+func (ac *Client) SetRateLimit(method string, limit RateLimit) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if limit.Rate <= 0 {
+		delete(ac.limiters, method)
+		return
+	}
+	if ac.limiters == nil {
+		ac.limiters = make(map[string]*rateLimiter)
+	}
+	ac.limiters[method] = newRateLimiter(limit)
+}
+
+func (ac *Client) waitRateLimit(ctx context.Context, method string) error {
+	ac.mu.Lock()
+	limiter, ok := ac.limiters[method]
+	if !ok {
+		limiter = ac.limiters[""]
+	}
+	ac.mu.Unlock()
+	if limiter == nil {
+		return nil
+	}
+	return limiter.wait(ctx)
+}
+
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, delay); err != nil {
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package anticaptcha
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit RateLimit
+		calls int
+		min   time.Duration
+		max   time.Duration
+	}{
+		{name: "within burst", limit: RateLimit{Rate: 10, Burst: 5}, calls: 5, max: 50 * time.Millisecond},
+		{name: "over burst", limit: RateLimit{Rate: 100, Burst: 5}, calls: 15, min: 90 * time.Millisecond, max: 300 * time.Millisecond},
+		{name: "zero burst allows one", limit: RateLimit{Rate: 50}, calls: 3, min: 35 * time.Millisecond, max: 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.limit)
+			started := time.Now()
+			for i := 0; i < tt.calls; i++ {
+				if err := limiter.wait(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(started); elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("%d calls took %v, want between %v and %v", tt.calls, elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRateLimiterCancelRefundsToken(t *testing.T) {
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 1})
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.tokens < -0.5 {
+		t.Errorf("cancelled wait kept its token: %v tokens left", limiter.tokens)
+	}
+}
+
+func TestSetRateLimit(t *testing.T) {
+	ac := NewClient("key")
+	ac.SetRateLimit("", RateLimit{Rate: 1, Burst: 1})
+	ac.SetRateLimit("createTask", RateLimit{Rate: 1000, Burst: 10})
+
+	tests := []struct {
+		method string
+		calls  int
+		slow   bool
+	}{
+		{method: "createTask", calls: 5},
+		{method: "getTaskResult", calls: 2, slow: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			var err error
+			for i := 0; i < tt.calls && err == nil; i++ {
+				err = ac.waitRateLimit(ctx, tt.method)
+			}
+			if slow := err != nil; slow != tt.slow {
+				t.Errorf("got error %v, want slow=%v", err, tt.slow)
+			}
+		})
+	}
+
+	ac.SetRateLimit("", RateLimit{})
+	if err := ac.waitRateLimit(context.Background(), "getTaskResult"); err != nil {
+		t.Errorf("removed limit still applies: %v", err)
+	}
+}
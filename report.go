@@ -0,0 +1,81 @@
+package anticaptcha
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var ErrReportNotSupported = errors.New("reporting is not supported for this task type")
+
+// Report tells the API whether the solution of the task was correct. The
+// report is sent to the endpoint matching taskType, for example
+// "ImageToTextTask", "RecaptchaV2TaskProxyless" or "HCaptchaTask". Only
+// reCAPTCHA accepts correct reports. Other combinations return
+// ErrReportNotSupported. Report uses the Client's own endpoint and key; tasks
+// created through Providers or with a KeyPool are reported with ReportResult.
+func (ac *Client) Report(ctx context.Context, taskType string, taskID int, correct bool) error {
+	return ac.report(ctx, ac.defaultProvider(), taskType, taskID, correct)
+}
+
+// ReportResult reports the task behind a solution to the provider which
+// solved it, with the key it was created with, routed by meta.TaskType like
+// Report.
+func (ac *Client) ReportResult(ctx context.Context, meta TaskMeta, correct bool) error {
+	task, err := ac.TaskByMeta(meta)
+	if err != nil {
+		return err
+	}
+	return task.Report(ctx, correct)
+}
+
+// reportLast reports the task of the most recent legacy solve to the provider
+// which solved it, with the key it was created with.
+func (ac *Client) reportLast(ctx context.Context, method string) error {
+	meta := ac.lastTask()
+	provider, err := ac.metaProvider(meta)
+	if err != nil {
+		return err
+	}
+	_, err = ac.request(ctx, provider, method, map[string]interface{}{
+		"clientKey": provider.ClientKey,
+		"taskId":    meta.TaskID,
+	})
+	return err
+}
+
+// Report tells the provider which solved the task whether the solution was
+// correct, see Client.Report. Handles rebuilt with TaskByID don't know their
+// task type and return ErrReportNotSupported.
+func (t *Task) Report(ctx context.Context, correct bool) error {
+	return t.client.report(ctx, t.provider, t.taskType, t.id, correct)
+}
+
+func (ac *Client) report(ctx context.Context, provider Provider, taskType string, taskID int, correct bool) error {
+	method := reportMethod(taskType, correct)
+	if method == "" {
+		return ErrReportNotSupported
+	}
+	_, err := ac.request(ctx, provider, method, map[string]interface{}{
+		"clientKey": provider.ClientKey,
+		"taskId":    taskID,
+	})
+	return err
+}
+
+func reportMethod(taskType string, correct bool) string {
+	switch {
+	case strings.HasPrefix(taskType, "Recaptcha"):
+		if correct {
+			return "reportCorrectRecaptcha"
+		}
+		return "reportIncorrectRecaptcha"
+	case correct:
+		return ""
+	case taskType == "ImageToTextTask", taskType == "ImageToCoordinatesTask":
+		return "reportIncorrectImageCaptcha"
+	case strings.HasPrefix(taskType, "HCaptcha"):
+		return "reportIncorrectHcaptcha"
+	}
+	return ""
+}
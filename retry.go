@@ -0,0 +1,110 @@
+package anticaptcha
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// RetryPolicy controls how the client retries failed requests and tasks.
+// Error codes in RetryRequestOn, as well as network errors, repeat the same
+// HTTP call. Error codes in RecreateOn discard the task and submit it again
+// with a new createTask call. MaxAttempts bounds both kinds of retries.
+//
+// A createTask call is only repeated after a network error if the request
+// never reached the API, such as a refused connection. A timeout after the
+// request was sent is returned as is, since the API may already have created
+// and charged for the task.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         float64
+	RecreateOn     []string
+	RetryRequestOn []string
+}
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RecreateOn: []string{
+			ErrCaptchaUnsolvable.Code,
+			ErrRecaptchaTimeout.Code,
+			ErrFailedLoadingWidget.Code,
+			ErrTaskCanceled.Code,
+		},
+		RetryRequestOn: []string{
+			ErrNoSlotAvailable.Code,
+		},
+	}
+}
+
+// Backoff returns the delay before the given retry attempt, starting from 1.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	return time.Duration(delay)
+}
+
+func (p *RetryPolicy) allows(attempt int) bool {
+	return p != nil && attempt < p.MaxAttempts
+}
+
+func (p *RetryPolicy) recreatesTask(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && containsCode(p.RecreateOn, apiErr.Code)
+}
+
+func (p *RetryPolicy) retriesRequest(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return containsCode(p.RetryRequestOn, apiErr.Code)
+	}
+	return repeatable(method, err)
+}
+
+// repeatable reports whether a call to method which failed with a transport
+// error may be sent again. createTask is only repeated when the request never
+// reached the API, so a task is never created and paid for twice.
+func repeatable(method string, err error) bool {
+	if method == "createTask" {
+		return notSent(err)
+	}
+	return unreachable(err)
+}
+
+// notSent reports whether err happened before the request was sent, while
+// resolving or connecting to the endpoint.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package anticaptcha_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anti-captcha/anticaptcha-go"
+	"github.com/anti-captcha/anticaptcha-go/anticaptchatest"
+)
+
+// flakyFront sits in front of an anticaptchatest.Server. It fails the first
+// failures calls of each method in fail and passes everything else on.
+type flakyFront struct {
+	*httptest.Server
+	mu       sync.Mutex
+	calls    map[string]int
+	fail     map[string]http.HandlerFunc
+	failures int
+}
+
+func newFlakyFront(srv *anticaptchatest.Server, failures int, fail map[string]http.HandlerFunc) *flakyFront {
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	f := &flakyFront{calls: make(map[string]int), fail: fail, failures: failures}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		f.mu.Lock()
+		f.calls[method]++
+		n := f.calls[method]
+		f.mu.Unlock()
+		if handler, ok := f.fail[method]; ok && (f.failures < 0 || n <= f.failures) {
+			handler(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	return f
+}
+
+func (f *flakyFront) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func badGateway(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "<html>502 Bad Gateway</html>", http.StatusBadGateway)
+}
+
+func hangUp(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	select {
+	case <-r.Context().Done():
+	case <-time.After(time.Second):
+	}
+}
+
+func testRetryPolicy() *anticaptcha.RetryPolicy {
+	policy := anticaptcha.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = time.Millisecond
+	return policy
+}
+
+func TestRetryRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		fail            map[string]http.HandlerFunc
+		failures        int
+		wantErr         bool
+		wantCreateTask  int
+		wantTaskResults int
+		wantCreated     int
+	}{
+		{
+			name:           "createTask 502 is not resent",
+			fail:           map[string]http.HandlerFunc{"createTask": badGateway},
+			failures:       -1,
+			wantErr:        true,
+			wantCreateTask: 1,
+		},
+		{
+			name:           "createTask timeout is not resent",
+			fail:           map[string]http.HandlerFunc{"createTask": hangUp},
+			failures:       -1,
+			wantErr:        true,
+			wantCreateTask: 1,
+		},
+		{
+			name:            "getTaskResult 502 is retried",
+			fail:            map[string]http.HandlerFunc{"getTaskResult": badGateway},
+			failures:        2,
+			wantCreateTask:  1,
+			wantTaskResults: 3,
+			wantCreated:     1,
+		},
+		{
+			name:            "getTaskResult gives up after MaxAttempts",
+			fail:            map[string]http.HandlerFunc{"getTaskResult": badGateway},
+			failures:        -1,
+			wantErr:         true,
+			wantCreateTask:  1,
+			wantTaskResults: 3,
+			wantCreated:     1,
+		},
+		{
+			name:            "getTaskResult timeout is retried",
+			fail:            map[string]http.HandlerFunc{"getTaskResult": hangUp},
+			failures:        1,
+			wantCreateTask:  1,
+			wantTaskResults: 2,
+			wantCreated:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			front := newFlakyFront(srv, tt.failures, tt.fail)
+			defer front.Close()
+			ac := srv.Client(
+				anticaptcha.WithBaseURL(front.URL),
+				anticaptcha.WithHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}),
+				anticaptcha.WithRetryPolicy(testRetryPolicy()),
+			)
+
+			_, err := ac.Solve(context.Background(), imageTask)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got := front.Calls("createTask"); got != tt.wantCreateTask {
+				t.Errorf("sent %d createTask requests, want %d", got, tt.wantCreateTask)
+			}
+			if got := front.Calls("getTaskResult"); got != tt.wantTaskResults {
+				t.Errorf("sent %d getTaskResult requests, want %d", got, tt.wantTaskResults)
+			}
+			if got := len(srv.Requests("createTask")); got > tt.wantCreated {
+				t.Errorf("the API received %d createTask requests, want at most %d", got, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestRetryCreateTaskNotSent(t *testing.T) {
+	srv := anticaptchatest.NewServer()
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	ac := srv.Client(
+		anticaptcha.WithBaseURL(down.URL),
+		anticaptcha.WithFallbackURLs(srv.URL),
+	)
+
+	// A refused connection never reached the API, so the fallback may create the task.
+	if _, err := ac.Solve(context.Background(), imageTask); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(srv.Requests("createTask")); got != 1 {
+		t.Errorf("the fallback received %d createTask requests, want 1", got)
+	}
+}
+
+func TestRetryErrorCodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         *anticaptcha.RetryPolicy
+		method         string
+		codes          []string
+		wantErr        error
+		wantCreateTask int
+	}{
+		{
+			name:           "no slot is resent",
+			policy:         testRetryPolicy(),
+			method:         "createTask",
+			codes:          []string{"ERROR_NO_SLOT_AVAILABLE", "ERROR_NO_SLOT_AVAILABLE"},
+			wantCreateTask: 3,
+		},
+		{
+			name:           "no slot gives up after MaxAttempts",
+			policy:         testRetryPolicy(),
+			method:         "createTask",
+			codes:          []string{"ERROR_NO_SLOT_AVAILABLE", "ERROR_NO_SLOT_AVAILABLE", "ERROR_NO_SLOT_AVAILABLE"},
+			wantErr:        anticaptcha.ErrNoSlotAvailable,
+			wantCreateTask: 3,
+		},
+		{
+			name:           "unsolvable recreates the task",
+			policy:         testRetryPolicy(),
+			method:         "getTaskResult",
+			codes:          []string{"ERROR_CAPTCHA_UNSOLVABLE"},
+			wantCreateTask: 2,
+		},
+		{
+			name:           "unsolvable without a policy",
+			method:         "getTaskResult",
+			codes:          []string{"ERROR_CAPTCHA_UNSOLVABLE"},
+			wantErr:        anticaptcha.ErrCaptchaUnsolvable,
+			wantCreateTask: 1,
+		},
+		{
+			name:           "other codes are returned",
+			policy:         testRetryPolicy(),
+			method:         "createTask",
+			codes:          []string{"ERROR_ZERO_CAPTCHA_FILESIZE"},
+			wantErr:        anticaptcha.ErrZeroCaptchaFilesize,
+			wantCreateTask: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := anticaptchatest.NewServer()
+			defer srv.Close()
+			for _, code := range tt.codes {
+				srv.InjectError(tt.method, code)
+			}
+			ac := srv.Client(anticaptcha.WithRetryPolicy(tt.policy))
+
+			_, err := ac.Solve(context.Background(), imageTask)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if got := len(srv.Requests("createTask")); got != tt.wantCreateTask {
+				t.Errorf("got %d createTask requests, want %d", got, tt.wantCreateTask)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy anticaptcha.RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "exponential up to the maximum",
+			policy: anticaptcha.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 2},
+			want:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:   "no maximum",
+			policy: anticaptcha.RetryPolicy{InitialBackoff: time.Second, Multiplier: 3},
+			want:   []time.Duration{time.Second, 3 * time.Second, 9 * time.Second},
+		},
+		{
+			name:   "multiplier below 1 is constant",
+			policy: anticaptcha.RetryPolicy{InitialBackoff: time.Second, Multiplier: 0.5},
+			want:   []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.policy.Backoff(i + 1); got != want {
+					t.Errorf("Backoff(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		policy := anticaptcha.RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Multiplier: 2, Jitter: 0.2}
+		for i := 0; i < 1000; i++ {
+			if got := policy.Backoff(5); got < 3200*time.Millisecond || got > 4800*time.Millisecond {
+				t.Fatalf("Backoff(5) = %v, want 4s ± 20%%", got)
+			}
+		}
+	})
+}
@@ -0,0 +1,406 @@
+package anticaptcha
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TaskMeta describes the task behind a solution, as reported by getTaskResult.
+// SolveTime is EndTime minus CreateTime, or the time measured by the client
+// when the API did not report both. KeyID identifies the key the task was
+// created with, which may come from a KeyPool, without revealing it.
+type TaskMeta struct {
+	TaskID     int
+	TaskType   string
+	Provider   string
+	KeyID      string
+	Cost       float64
+	IP         string
+	CreateTime time.Time
+	EndTime    time.Time
+	SolveCount int
+	SolveTime  time.Duration
+}
+
+type ImageSolution struct {
+	TaskMeta
+	Text string
+	URL  string
+}
+
+type RecaptchaSolution struct {
+	TaskMeta
+	Token   string
+	Cookies map[string]string
+}
+
+type HcaptchaSolution struct {
+	TaskMeta
+	Token     string
+	RespKey   string
+	UserAgent string
+}
+
+type TurnstileSolution struct {
+	TaskMeta
+	Token     string
+	UserAgent string
+}
+
+// TokenSolution is returned for captchas which are solved with a single
+// token: FunCaptcha, Prosopo, Friendly Captcha and Amazon WAF.
+type TokenSolution struct {
+	TaskMeta
+	Token string
+}
+
+type GeeTestV3Solution struct {
+	TaskMeta
+	Challenge string
+	Validate  string
+	Seccode   string
+}
+
+type GeeTestV4Solution struct {
+	TaskMeta
+	CaptchaID     string
+	LotNumber     string
+	PassToken     string
+	GenTime       string
+	CaptchaOutput string
+}
+
+type AntiGateSolution struct {
+	TaskMeta
+	URL          string
+	Domain       string
+	Cookies      map[string]string
+	LocalStorage map[string]string
+	Fingerprint  map[string]interface{}
+}
+
+type CoordinatesSolution struct {
+	TaskMeta
+	Coordinates [][]int
+}
+
+func (ac *Client) SolveImageResult(ctx context.Context, body string, settings ImageSettings) (*ImageSolution, error) {
+	result, err := ac.Solve(ctx, imageTask(body, settings))
+	if err != nil {
+		return nil, err
+	}
+	return &ImageSolution{
+		TaskMeta: result.TaskMeta,
+		Text:     stringValue(result.Solution, "text"),
+		URL:      stringValue(result.Solution, "url"),
+	}, nil
+}
+
+func (ac *Client) SolveRecaptchaV2Result(ctx context.Context, recaptcha RecaptchaV2) (*RecaptchaSolution, error) {
+	return ac.solveRecaptcha(ctx, recaptchaV2Task(recaptcha, false))
+}
+
+func (ac *Client) SolveRecaptchaV2ProxyOnResult(ctx context.Context, recaptcha RecaptchaV2) (*RecaptchaSolution, error) {
+	proxy, err := ac.proxyFor(recaptcha.Proxy, recaptcha.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	recaptcha.Proxy = proxy
+	solution, err := ac.solveRecaptcha(ctx, recaptchaV2Task(recaptcha, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) SolveRecaptchaV3Result(ctx context.Context, recaptcha RecaptchaV3) (*RecaptchaSolution, error) {
+	return ac.solveRecaptcha(ctx, recaptchaV3Task(recaptcha))
+}
+
+func (ac *Client) solveRecaptcha(ctx context.Context, task map[string]interface{}) (*RecaptchaSolution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &RecaptchaSolution{
+		TaskMeta: result.TaskMeta,
+		Token:    stringValue(result.Solution, "gRecaptchaResponse"),
+		Cookies:  stringMap(result.Solution["cookies"]),
+	}, nil
+}
+
+func (ac *Client) SolveHcaptchaResult(ctx context.Context, hcaptcha Hcaptcha) (*HcaptchaSolution, error) {
+	return ac.solveHcaptcha(ctx, hcaptchaTask(hcaptcha, false))
+}
+
+func (ac *Client) SolveHcaptchaProxyOnResult(ctx context.Context, hcaptcha Hcaptcha) (*HcaptchaSolution, error) {
+	proxy, err := ac.proxyFor(hcaptcha.Proxy, hcaptcha.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	hcaptcha.Proxy = proxy
+	solution, err := ac.solveHcaptcha(ctx, hcaptchaTask(hcaptcha, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) solveHcaptcha(ctx context.Context, task map[string]interface{}) (*HcaptchaSolution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &HcaptchaSolution{
+		TaskMeta:  result.TaskMeta,
+		Token:     stringValue(result.Solution, "gRecaptchaResponse"),
+		RespKey:   stringValue(result.Solution, "respKey"),
+		UserAgent: stringValue(result.Solution, "userAgent"),
+	}, nil
+}
+
+func (ac *Client) SolveTurnstileResult(ctx context.Context, turnstile Turnstile) (*TurnstileSolution, error) {
+	return ac.solveTurnstile(ctx, turnstileTask(turnstile, false))
+}
+
+func (ac *Client) SolveTurnstileProxyOnResult(ctx context.Context, turnstile Turnstile) (*TurnstileSolution, error) {
+	proxy, err := ac.proxyFor(turnstile.Proxy, turnstile.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	turnstile.Proxy = proxy
+	solution, err := ac.solveTurnstile(ctx, turnstileTask(turnstile, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) solveTurnstile(ctx context.Context, task map[string]interface{}) (*TurnstileSolution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &TurnstileSolution{
+		TaskMeta:  result.TaskMeta,
+		Token:     stringValue(result.Solution, "token"),
+		UserAgent: stringValue(result.Solution, "userAgent"),
+	}, nil
+}
+
+func (ac *Client) SolveFunCaptchaResult(ctx context.Context, funcaptcha FunCaptcha) (*TokenSolution, error) {
+	return ac.solveToken(ctx, funCaptchaTask(funcaptcha, false))
+}
+
+func (ac *Client) SolveFunCaptchaProxyOnResult(ctx context.Context, funcaptcha FunCaptcha) (*TokenSolution, error) {
+	proxy, err := ac.proxyFor(funcaptcha.Proxy, funcaptcha.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	funcaptcha.Proxy = proxy
+	solution, err := ac.solveToken(ctx, funCaptchaTask(funcaptcha, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) SolveProsopoResult(ctx context.Context, prosopo Prosopo) (*TokenSolution, error) {
+	return ac.solveToken(ctx, prosopoTask(prosopo, false))
+}
+
+func (ac *Client) SolveProsopoProxyOnResult(ctx context.Context, prosopo Prosopo) (*TokenSolution, error) {
+	proxy, err := ac.proxyFor(prosopo.Proxy, prosopo.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	prosopo.Proxy = proxy
+	solution, err := ac.solveToken(ctx, prosopoTask(prosopo, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) SolveFriendlyCaptchaResult(ctx context.Context, friendlyCaptcha FriendlyCaptcha) (*TokenSolution, error) {
+	return ac.solveToken(ctx, friendlyCaptchaTask(friendlyCaptcha, false))
+}
+
+func (ac *Client) SolveFriendlyCaptchaProxyOnResult(ctx context.Context, friendlyCaptcha FriendlyCaptcha) (*TokenSolution, error) {
+	proxy, err := ac.proxyFor(friendlyCaptcha.Proxy, friendlyCaptcha.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	friendlyCaptcha.Proxy = proxy
+	solution, err := ac.solveToken(ctx, friendlyCaptchaTask(friendlyCaptcha, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) SolveAmazonResult(ctx context.Context, amazonCaptcha AmazonCaptcha) (*TokenSolution, error) {
+	return ac.solveToken(ctx, amazonTask(amazonCaptcha, false))
+}
+
+func (ac *Client) SolveAmazonProxyOnResult(ctx context.Context, amazonCaptcha AmazonCaptcha) (*TokenSolution, error) {
+	proxy, err := ac.proxyFor(amazonCaptcha.Proxy, amazonCaptcha.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	amazonCaptcha.Proxy = proxy
+	solution, err := ac.solveToken(ctx, amazonTask(amazonCaptcha, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) solveToken(ctx context.Context, task map[string]interface{}) (*TokenSolution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenSolution{
+		TaskMeta: result.TaskMeta,
+		Token:    stringValue(result.Solution, "token"),
+	}, nil
+}
+
+func (ac *Client) SolveGeeTestV3Result(ctx context.Context, geetest GeeTest) (*GeeTestV3Solution, error) {
+	geetest.Version = 3
+	return ac.solveGeeTestV3(ctx, geeTestTask(geetest, false))
+}
+
+func (ac *Client) SolveGeeTestV3ProxyOnResult(ctx context.Context, geetest GeeTest) (*GeeTestV3Solution, error) {
+	proxy, err := ac.proxyFor(geetest.Proxy, geetest.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	geetest.Proxy = proxy
+	geetest.Version = 3
+	solution, err := ac.solveGeeTestV3(ctx, geeTestTask(geetest, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) solveGeeTestV3(ctx context.Context, task map[string]interface{}) (*GeeTestV3Solution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &GeeTestV3Solution{
+		TaskMeta:  result.TaskMeta,
+		Challenge: stringValue(result.Solution, "challenge"),
+		Validate:  stringValue(result.Solution, "validate"),
+		Seccode:   stringValue(result.Solution, "seccode"),
+	}, nil
+}
+
+func (ac *Client) SolveGeeTestV4Result(ctx context.Context, geetest GeeTest) (*GeeTestV4Solution, error) {
+	geetest.Version = 4
+	return ac.solveGeeTestV4(ctx, geeTestTask(geetest, false))
+}
+
+func (ac *Client) SolveGeeTestV4ProxyOnResult(ctx context.Context, geetest GeeTest) (*GeeTestV4Solution, error) {
+	proxy, err := ac.proxyFor(geetest.Proxy, geetest.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	geetest.Proxy = proxy
+	geetest.Version = 4
+	solution, err := ac.solveGeeTestV4(ctx, geeTestTask(geetest, true))
+	ac.reportProxy(proxy, err)
+	return solution, err
+}
+
+func (ac *Client) solveGeeTestV4(ctx context.Context, task map[string]interface{}) (*GeeTestV4Solution, error) {
+	result, err := ac.Solve(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	return &GeeTestV4Solution{
+		TaskMeta:      result.TaskMeta,
+		CaptchaID:     stringValue(result.Solution, "captcha_id"),
+		LotNumber:     stringValue(result.Solution, "lot_number"),
+		PassToken:     stringValue(result.Solution, "pass_token"),
+		GenTime:       stringValue(result.Solution, "gen_time"),
+		CaptchaOutput: stringValue(result.Solution, "captcha_output"),
+	}, nil
+}
+
+func (ac *Client) SolveAntiGateResult(ctx context.Context, antigate AntiGate) (*AntiGateSolution, error) {
+	proxy, err := ac.optionalProxy(antigate.Proxy, antigate.WebsiteURL)
+	if err != nil {
+		return nil, err
+	}
+	antigate.Proxy = proxy
+	result, err := ac.Solve(ctx, antiGateTask(antigate))
+	ac.reportProxy(proxy, err)
+	if err != nil {
+		return nil, err
+	}
+	return newAntiGateSolution(result), nil
+}
+
+func newAntiGateSolution(result *TaskResult) *AntiGateSolution {
+	fingerprint, _ := result.Solution["fingerprint"].(map[string]interface{})
+	return &AntiGateSolution{
+		TaskMeta:     result.TaskMeta,
+		URL:          stringValue(result.Solution, "url"),
+		Domain:       stringValue(result.Solution, "domain"),
+		Cookies:      stringMap(result.Solution["cookies"]),
+		LocalStorage: stringMap(result.Solution["localStorage"]),
+		Fingerprint:  fingerprint,
+	}
+}
+
+func (ac *Client) SolveImageToCoordinatesResult(ctx context.Context, body string, settings ImageToCoordinates) (*CoordinatesSolution, error) {
+	result, err := ac.Solve(ctx, imageToCoordinatesTask(body, settings))
+	if err != nil {
+		return nil, err
+	}
+	solution := &CoordinatesSolution{TaskMeta: result.TaskMeta}
+	points, _ := result.Solution["coordinates"].([]interface{})
+	for _, point := range points {
+		values, _ := point.([]interface{})
+		coordinates := make([]int, 0, len(values))
+		for _, value := range values {
+			if number, ok := value.(float64); ok {
+				coordinates = append(coordinates, int(number))
+			}
+		}
+		solution.Coordinates = append(solution.Coordinates, coordinates)
+	}
+	return solution, nil
+}
+
+func stringValue(solution map[string]interface{}, key string) string {
+	value, _ := solution[key].(string)
+	return value
+}
+
+func unixTime(value interface{}) time.Time {
+	seconds := floatValue(value)
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+// floatValue accepts both numbers and numeric strings, since the API
+// returns some amounts, like cost, as strings.
+func floatValue(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return f
+	}
+	return 0
+}
+
+func stringMap(value interface{}) map[string]string {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			result[key] = s
+		} else {
+			result[key] = fmt.Sprint(value)
+		}
+	}
+	return result
+}
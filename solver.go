@@ -0,0 +1,22 @@
+package anticaptcha
+
+import "context"
+
+// Solver solves a raw task, as built for the createTask API method.
+type Solver interface {
+	Solve(ctx context.Context, task map[string]interface{}) (*TaskResult, error)
+}
+
+type BalanceChecker interface {
+	GetBalanceContext(ctx context.Context) (float64, error)
+}
+
+type Reporter interface {
+	Report(ctx context.Context, taskType string, taskID int, correct bool) error
+}
+
+var (
+	_ Solver         = (*Client)(nil)
+	_ BalanceChecker = (*Client)(nil)
+	_ Reporter       = (*Client)(nil)
+)
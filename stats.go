@@ -0,0 +1,186 @@
+package anticaptcha
+
+import (
+	"context"
+	"time"
+)
+
+// Queue IDs accepted by GetQueueStats.
+const (
+	QueueImageEnglish                   = 1
+	QueueImageRussian                   = 2
+	QueueRecaptchaV2                    = 5
+	QueueRecaptchaV2Proxyless           = 6
+	QueueFunCaptcha                     = 7
+	QueueFunCaptchaProxyless            = 10
+	QueueRecaptchaV3Score03             = 18
+	QueueRecaptchaV3Score07             = 19
+	QueueRecaptchaV3Score09             = 20
+	QueueHcaptcha                       = 21
+	QueueHcaptchaProxyless              = 22
+	QueueRecaptchaV2Enterprise          = 23
+	QueueRecaptchaV2EnterpriseProxyless = 24
+	QueueAntiGate                       = 25
+	QueueTurnstile                      = 26
+	QueueTurnstileProxyless             = 27
+	QueueAmazon                         = 28
+	QueueAmazonProxyless                = 29
+)
+
+// QueueStats is the current load of one queue. Load is the percentage of busy
+// workers, Bid the price per task in USD and Speed the average solving time
+// in seconds.
+type QueueStats struct {
+	Waiting int
+	Load    float64
+	Bid     float64
+	Speed   float64
+	Total   int
+}
+
+// GetQueueStats returns the load and price of a queue, see the Queue* IDs.
+func (ac *Client) GetQueueStats(ctx context.Context, queueID int) (*QueueStats, error) {
+	response, err := ac.JSONRequestContext(ctx, "getQueueStats", map[string]interface{}{"queueId": queueID})
+	if err != nil {
+		return nil, err
+	}
+	return &QueueStats{
+		Waiting: int(floatValue(response["waiting"])),
+		Load:    floatValue(response["load"]),
+		Bid:     floatValue(response["bid"]),
+		Speed:   floatValue(response["speed"]),
+		Total:   int(floatValue(response["total"])),
+	}, nil
+}
+
+// Scopes accepted by SpendingStatsFilter.
+const (
+	SpendingScopeHour = "hour"
+	SpendingScopeDay  = "day"
+)
+
+// SpendingStatsFilter narrows GetSpendingStats. Date is the end of the
+// reported period and defaults to now. Scope splits the period by hour over
+// one day or by day over one month. Queue is a queue name like
+// "English ImageToText". Zero fields are not sent.
+type SpendingStatsFilter struct {
+	Date   time.Time
+	Scope  string
+	Queue  string
+	SoftID int
+	IP     string
+}
+
+// SpendingPeriod is the number of tasks and money spent in one period.
+type SpendingPeriod struct {
+	From   time.Time
+	Till   time.Time
+	Volume int
+	Money  float64
+}
+
+// GetSpendingStats returns the account's spending split into periods.
+func (ac *Client) GetSpendingStats(ctx context.Context, filter SpendingStatsFilter) ([]SpendingPeriod, error) {
+	payload := map[string]interface{}{"clientKey": ac.ClientKey}
+	if !filter.Date.IsZero() {
+		payload["date"] = filter.Date.Unix()
+	}
+	if filter.Scope != "" {
+		payload["scope"] = filter.Scope
+	}
+	if filter.Queue != "" {
+		payload["queue"] = filter.Queue
+	}
+	if filter.SoftID != 0 {
+		payload["softId"] = filter.SoftID
+	}
+	if filter.IP != "" {
+		payload["ip"] = filter.IP
+	}
+	response, err := ac.JSONRequestContext(ctx, "getSpendingStats", payload)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := response["data"].([]interface{})
+	periods := make([]SpendingPeriod, 0, len(data))
+	for _, item := range data {
+		period, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, ErrInvalidResponse
+		}
+		periods = append(periods, SpendingPeriod{
+			From:   unixTime(period["dateFrom"]),
+			Till:   unixTime(period["dateTill"]),
+			Volume: int(floatValue(period["volume"])),
+			Money:  floatValue(period["money"]),
+		})
+	}
+	return periods, nil
+}
+
+// Modes accepted by GetAppStats.
+const (
+	AppStatsErrors    = "errors"
+	AppStatsViews     = "views"
+	AppStatsDownloads = "downloads"
+	AppStatsUsers     = "users"
+	AppStatsMoney     = "money"
+)
+
+// AppStats is the chart data of an application registered with a softId.
+type AppStats struct {
+	FromDate string
+	ToDate   string
+	Series   []AppStatsSeries
+}
+
+type AppStatsSeries struct {
+	Name   string
+	Points []AppStatsPoint
+}
+
+type AppStatsPoint struct {
+	Date  string
+	Value float64
+}
+
+// GetAppStats returns the statistics of the application with softID, see the
+// AppStats* modes. An empty mode uses the API default.
+func (ac *Client) GetAppStats(ctx context.Context, softID int, mode string) (*AppStats, error) {
+	payload := map[string]interface{}{
+		"clientKey": ac.ClientKey,
+		"softId":    softID,
+	}
+	if mode != "" {
+		payload["mode"] = mode
+	}
+	response, err := ac.JSONRequestContext(ctx, "getAppStats", payload)
+	if err != nil {
+		return nil, err
+	}
+	stats := &AppStats{
+		FromDate: stringValue(response, "fromDate"),
+		ToDate:   stringValue(response, "toDate"),
+	}
+	chartData, _ := response["chartData"].([]interface{})
+	for _, item := range chartData {
+		chart, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, ErrInvalidResponse
+		}
+		series := AppStatsSeries{Name: stringValue(chart, "name")}
+		data, _ := chart["data"].([]interface{})
+		for _, point := range data {
+			p, ok := point.(map[string]interface{})
+			if !ok {
+				return nil, ErrInvalidResponse
+			}
+			series.Points = append(series.Points, AppStatsPoint{
+				Date:  stringValue(p, "date"),
+				Value: floatValue(p["value"]),
+			})
+		}
+		stats.Series = append(stats.Series, series)
+	}
+	return stats, nil
+}
@@ -0,0 +1,191 @@
+package anticaptcha
+
+func imageTask(body string, settings ImageSettings) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         "ImageToTextTask",
+		"body":         body,
+		"phrase":       settings.Phrase,
+		"case":         settings.CaseSensitive,
+		"numeric":      settings.Numeric,
+		"comment":      settings.Comment,
+		"math":         settings.MathOperation,
+		"minLength":    settings.MinLength,
+		"maxLength":    settings.MaxLength,
+		"languagePool": settings.LanguagePool,
+	}
+}
+
+func recaptchaV2Task(recaptcha RecaptchaV2, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":                "RecaptchaV2TaskProxyless",
+		"websiteURL":          recaptcha.WebsiteURL,
+		"websiteKey":          recaptcha.WebsiteKey,
+		"websiteSToken":       recaptcha.WebsiteSToken,
+		"recaptchaDataSValue": recaptcha.DataSValue,
+	}
+	if recaptcha.IsInvisible {
+		task["isInvisible"] = true
+	}
+	if proxyOn {
+		task["type"] = "RecaptchaV2Task"
+		task["userAgent"] = recaptcha.UserAgent
+		setProxy(task, recaptcha.Proxy)
+	}
+	return task
+}
+
+func recaptchaV3Task(recaptcha RecaptchaV3) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         "RecaptchaV3TaskProxyless",
+		"websiteURL":   recaptcha.WebsiteURL,
+		"websiteKey":   recaptcha.WebsiteKey,
+		"minScore":     recaptcha.MinScore,
+		"pageAction":   recaptcha.PageAction,
+		"isEnterprise": recaptcha.IsEnterprise,
+		"apiDomain":    recaptcha.APIDomain,
+	}
+}
+
+func hcaptchaTask(hcaptcha Hcaptcha, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":              "HCaptchaTaskProxyless",
+		"websiteURL":        hcaptcha.WebsiteURL,
+		"websiteKey":        hcaptcha.WebsiteKey,
+		"isEnterprise":      hcaptcha.IsEnterprise,
+		"enterprisePayload": hcaptcha.EnterprisePayload,
+	}
+	if hcaptcha.IsInvisible {
+		task["isInvisible"] = true
+	}
+	if proxyOn {
+		task["type"] = "HCaptchaTask"
+		setProxy(task, hcaptcha.Proxy)
+	}
+	return task
+}
+
+func funCaptchaTask(funcaptcha FunCaptcha, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":                     "FunCaptchaTaskProxyless",
+		"websiteURL":               funcaptcha.WebsiteURL,
+		"websitePublicKey":         funcaptcha.WebsitePublicKey,
+		"funcaptchaApiJSSubdomain": funcaptcha.ApiSubdomain,
+		"data":                     funcaptcha.DataBlob,
+	}
+	if proxyOn {
+		task["type"] = "FunCaptchaTask"
+		setProxy(task, funcaptcha.Proxy)
+	}
+	return task
+}
+
+func turnstileTask(turnstile Turnstile, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":        "TurnstileTaskProxyless",
+		"websiteURL":  turnstile.WebsiteURL,
+		"websiteKey":  turnstile.WebsiteKey,
+		"action":      turnstile.Action,
+		"cData":       turnstile.CData,
+		"chlPageData": turnstile.ChlPageData,
+	}
+	if proxyOn {
+		task["type"] = "TurnstileTask"
+		setProxy(task, turnstile.Proxy)
+	}
+	return task
+}
+
+func prosopoTask(prosopo Prosopo, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":       "ProsopoTaskProxyless",
+		"websiteURL": prosopo.WebsiteURL,
+		"websiteKey": prosopo.WebsiteKey,
+	}
+	if proxyOn {
+		task["type"] = "ProsopoTask"
+		setProxy(task, prosopo.Proxy)
+	}
+	return task
+}
+
+func friendlyCaptchaTask(friendlyCaptcha FriendlyCaptcha, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":       "FriendlyCaptchaTaskProxyless",
+		"websiteURL": friendlyCaptcha.WebsiteURL,
+		"websiteKey": friendlyCaptcha.WebsiteKey,
+	}
+	if proxyOn {
+		task["type"] = "FriendlyCaptchaTask"
+		setProxy(task, friendlyCaptcha.Proxy)
+	}
+	return task
+}
+
+func amazonTask(amazonCaptcha AmazonCaptcha, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":            "AmazonTaskProxyless",
+		"websiteURL":      amazonCaptcha.WebsiteURL,
+		"websiteKey":      amazonCaptcha.WebsiteKey,
+		"wafType":         amazonCaptcha.WafType,
+		"iv":              amazonCaptcha.Iv,
+		"context":         amazonCaptcha.Context,
+		"captchaScript":   amazonCaptcha.CaptchaScript,
+		"challengeScript": amazonCaptcha.ChallengeScript,
+		"jsapiScript":     amazonCaptcha.JsapiScript,
+	}
+	if proxyOn {
+		task["type"] = "AmazonTask"
+		setProxy(task, amazonCaptcha.Proxy)
+	}
+	return task
+}
+
+func geeTestTask(geetest GeeTest, proxyOn bool) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":                      "GeeTestTaskProxyless",
+		"websiteURL":                geetest.WebsiteURL,
+		"gt":                        geetest.Gt,
+		"challenge":                 geetest.Challenge,
+		"geetestApiServerSubdomain": geetest.ApiSubdomain,
+		"version":                   geetest.Version,
+		"initParameters":            geetest.InitParameters,
+	}
+	if proxyOn {
+		task["type"] = "GeeTestTask"
+		setProxy(task, geetest.Proxy)
+	}
+	return task
+}
+
+func antiGateTask(antigate AntiGate) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":              "AntiGateTask",
+		"websiteURL":        antigate.WebsiteURL,
+		"templateName":      antigate.TemplateName,
+		"variables":         antigate.Variables,
+		"domainsOfInterest": antigate.DomainsOfInterest,
+	}
+	setProxy(task, antigate.Proxy)
+	return task
+}
+
+func imageToCoordinatesTask(body string, settings ImageToCoordinates) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "ImageToCoordinatesTask",
+		"body":       body,
+		"comment":    settings.Comment,
+		"mode":       settings.Mode,
+		"websiteURL": settings.WebsiteURL,
+	}
+}
+
+func setProxy(task map[string]interface{}, proxy *Proxy) {
+	if proxy == nil {
+		return
+	}
+	task["proxyType"] = proxy.Type
+	task["proxyAddress"] = proxy.IPAddress
+	task["proxyPort"] = proxy.Port
+	task["proxyLogin"] = proxy.Login
+	task["proxyPassword"] = proxy.Password
+}